@@ -3,7 +3,6 @@ package bloom
 import (
 	btcwire "github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
-	"github.com/sammyne/bip37/wire"
 )
 
 // AddOutPoint takes a COutPoint into record, which is actually the
@@ -18,23 +17,18 @@ func (f *Filter) AddOutPoint(out *btcwire.OutPoint) error {
 // MatchOutPoint checks if the given COutPoint is possibly recorded
 // in the bit pattern of the filter
 func (f *Filter) MatchOutPoint(out *btcwire.OutPoint) bool {
-	f.mtx.Lock()
-	defer f.mtx.Unlock()
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
 
 	return f.match(marshalOutPoint(out))
 }
 
 // MatchTx checks if the tx matches the bit pattern of filter
 func (f *Filter) MatchTx(tx *btcutil.Tx) bool {
-	f.mtx.Lock()
-	defer f.mtx.Unlock()
-
-	bak := f.snapshot.Flags
-	f.snapshot.Flags = wire.UpdateNone
-	ok := f.matchTxAndUpdate(tx)
-	f.snapshot.Flags = bak
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
 
-	return ok
+	return f.matchTxAndUpdate(tx, false)
 }
 
 // MatchTxAndUpdate checks if the tx matches the bit pattern of filter and
@@ -43,5 +37,52 @@ func (f *Filter) MatchTxAndUpdate(tx *btcutil.Tx) bool {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
 
-	return f.matchTxAndUpdate(tx)
+	return f.matchTxAndUpdate(tx, true)
+}
+
+// MatchTxAndTrack works as MatchTxAndUpdate but also returns the OutPoints
+// added into the filter by the update, so that callers can keep track of
+// them, e.g. to rebuild the filter later
+func (f *Filter) MatchTxAndTrack(tx *btcutil.Tx) (bool, []btcwire.OutPoint) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	var tracked []btcwire.OutPoint
+	ok := f.matchElementsAndUpdate(ExtractElements(tx), true, &tracked)
+
+	return ok, tracked
+}
+
+// MatchTxWithPrevOuts works as MatchTx but also matches each input by the
+// elements of the output it spends, which are resolved by fetcher
+func (f *Filter) MatchTxWithPrevOuts(tx *btcutil.Tx,
+	fetcher PrevOutFetcher) bool {
+	return f.MatchElements(ExtractElementsWithPrevOuts(tx, fetcher))
+}
+
+// MatchTxAndUpdateWithPrevOuts works as MatchTxAndUpdate but also matches
+// each input by the elements of the output it spends, which are resolved by
+// fetcher
+func (f *Filter) MatchTxAndUpdateWithPrevOuts(tx *btcutil.Tx,
+	fetcher PrevOutFetcher) bool {
+	return f.MatchElementsAndUpdate(ExtractElementsWithPrevOuts(tx, fetcher))
+}
+
+// MatchElements checks if the pre-extracted elements of a tx match the bit
+// pattern of filter, which is the batched counterpart of MatchTx
+func (f *Filter) MatchElements(elems *TxElements) bool {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return f.matchElementsAndUpdate(elems, false, nil)
+}
+
+// MatchElementsAndUpdate checks if the pre-extracted elements of a tx match
+// the bit pattern of filter and update the bit pattern accordingly, which is
+// the batched counterpart of MatchTxAndUpdate
+func (f *Filter) MatchElementsAndUpdate(elems *TxElements) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	return f.matchElementsAndUpdate(elems, true, nil)
 }
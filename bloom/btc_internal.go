@@ -6,6 +6,7 @@ import (
 	"github.com/sammyne/bip37/wire"
 
 	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
 
 	"github.com/btcsuite/btcutil"
 )
@@ -20,33 +21,58 @@ func (f *Filter) addOutPoint(txHash []byte, index uint32) error {
 }
 
 // matchTxAndUpdate implements the matching algorithm as https://github.com/bitcoin/bips/blob/master/bip-0037.mediawiki#filter-matching-algorithm
-func (f *Filter) matchTxAndUpdate(tx *btcutil.Tx) bool {
+func (f *Filter) matchTxAndUpdate(tx *btcutil.Tx, update bool) bool {
+	return f.matchElementsAndUpdate(ExtractElements(tx), update, nil)
+}
+
+// matchElementsAndUpdate is the matching algorithm working on the
+// pre-extracted elements of a tx. The bit pattern is only updated if update
+// is true, in which case the caller must hold the write lock. The OutPoints
+// added by the update are appended to tracked if it isn't nil
+func (f *Filter) matchElementsAndUpdate(elems *TxElements, update bool,
+	tracked *[]btcwire.OutPoint) bool {
+	if nil == f.snapshot {
+		return false
+	}
+
+	flags := wire.UpdateNone
+	if update {
+		flags = f.snapshot.Flags
+	}
+
 	// check tx hash
-	txHash := tx.Hash()[:]
-	ok := f.match(txHash)
+	ok := f.match(elems.Hash)
 
 	// check elements in public key script of tx output
-	for idx, out := range tx.MsgTx().TxOut {
-		data, err := txscript.PushedData(out.PkScript)
-		if nil != err {
-			continue // skip the unexpected pushed data
-		}
-
-		for _, elem := range data {
+	for idx, out := range elems.Outputs {
+		for _, elem := range out.Pushes {
 			if !f.match(elem) {
 				continue // skip the negative
 			}
 
 			ok = true
 			// add the OutPoint as specified
-			switch f.snapshot.Flags {
+			var updated bool
+			switch flags {
 			case wire.UpdateAll:
-				f.addOutPoint(txHash, uint32(idx))
+				updated = true
 			case wire.UpdateP2PubKeyOnly:
-				if C := txscript.GetScriptClass(
-					out.PkScript); txscript.PubKeyTy == C || txscript.MultiSigTy == C {
-					f.addOutPoint(txHash, uint32(idx))
-				}
+				updated = txscript.PubKeyTy == out.Class ||
+					txscript.MultiSigTy == out.Class
+			case wire.UpdateP2PubKeyOrScriptHash:
+				updated = txscript.PubKeyTy == out.Class ||
+					txscript.MultiSigTy == out.Class ||
+					txscript.ScriptHashTy == out.Class ||
+					txscript.WitnessV0ScriptHashTy == out.Class
+			}
+
+			if updated {
+				f.addOutPoint(elems.Hash, uint32(idx))
+			}
+			if updated && nil != tracked {
+				out := btcwire.OutPoint{Index: uint32(idx)}
+				copy(out.Hash[:], elems.Hash)
+				*tracked = append(*tracked, out)
 			}
 			break
 		}
@@ -58,17 +84,18 @@ func (f *Filter) matchTxAndUpdate(tx *btcutil.Tx) bool {
 	}
 
 	// check OutPoint corresponding to tx input
-	for _, in := range tx.MsgTx().TxIn {
-		if f.match(marshalOutPoint(&in.PreviousOutPoint)) {
+	for _, in := range elems.Inputs {
+		if f.match(in.OutPoint) {
 			return true
 		}
 
-		data, err := txscript.PushedData(in.SignatureScript)
-		if nil != err {
-			continue
+		for _, elem := range in.Pushes {
+			if f.match(elem) {
+				return true
+			}
 		}
 
-		for _, elem := range data {
+		for _, elem := range in.PrevOutPushes {
 			if f.match(elem) {
 				return true
 			}
@@ -532,3 +532,91 @@ func TestFilter_MatchTxAndUpdate_p2PubKeyOnly(t *testing.T) {
 		}
 	}
 }
+
+func TestFilter_MatchTxAndTrack(t *testing.T) {
+	block := bip37.ReadBlock(t)
+	tx := btcutil.NewTx(block.Transactions[1])
+
+	testCases := []struct {
+		flags  wire.BloomUpdateType
+		expect int
+	}{
+		{wire.UpdateNone, 0},
+		{wire.UpdateAll, 1},
+		{wire.UpdateP2PubKeyOnly, 0}, // the output is P2PKH
+	}
+
+	for i, c := range testCases {
+		filter := bloom.New(10, 0.000001, c.flags, bloom.Tweak)
+		filter.Add(bip37.Unhexlify("1b8dd13b994bcfc787b32aeadf58ccb3615cbd54"))
+
+		ok, tracked := filter.MatchTxAndTrack(tx)
+		if !ok {
+			t.Fatalf("#%d matching is expected", i)
+		}
+
+		if len(tracked) != c.expect {
+			t.Fatalf("#%d invalid #(tracked): got %d, expect %d", i, len(tracked),
+				c.expect)
+		}
+
+		for j, out := range tracked {
+			if !out.Hash.IsEqual(tx.Hash()) {
+				t.Fatalf("#%d-%d invalid hash: got %s, expect %s", i, j, out.Hash,
+					tx.Hash())
+			}
+
+			if !filter.MatchOutPoint(&out) {
+				t.Fatalf("#%d-%d tracked OutPoint isn't added", i, j)
+			}
+		}
+	}
+}
+
+func TestFilter_MatchTxAndUpdate_p2PubKeyOrScriptHash(t *testing.T) {
+	scriptHash := bip37.Unhexlify("b5a2c786d9ef4658287ced5914b37a1b4aa32eee")
+	witnessScriptHash := bip37.Unhexlify("1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262")
+
+	p2sh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_HASH160).
+		AddData(scriptHash).AddOp(txscript.OP_EQUAL).Script()
+	p2wsh, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).
+		AddData(witnessScriptHash).Script()
+
+	msg := btcwire.NewMsgTx(btcwire.TxVersion)
+	msg.AddTxIn(btcwire.NewTxIn(bip37.NewOutPoint(make([]byte, 32), 0), nil, nil))
+	msg.AddTxOut(btcwire.NewTxOut(1000, p2sh))
+	msg.AddTxOut(btcwire.NewTxOut(1000, p2wsh))
+	tx := btcutil.NewTx(msg)
+
+	testCases := []struct {
+		flags  wire.BloomUpdateType
+		expect int
+	}{
+		{wire.UpdateP2PubKeyOnly, 0},
+		{wire.UpdateP2PubKeyOrScriptHash, 2},
+		{wire.UpdateAll, 2},
+	}
+
+	for i, c := range testCases {
+		filter := bloom.New(10, 0.000001, c.flags, bloom.Tweak)
+		filter.Add(scriptHash)
+		filter.Add(witnessScriptHash)
+
+		ok, tracked := filter.MatchTxAndTrack(tx)
+		if !ok {
+			t.Fatalf("#%d matching is expected", i)
+		}
+
+		if len(tracked) != c.expect {
+			t.Fatalf("#%d invalid #(tracked): got %d, expect %d", i, len(tracked),
+				c.expect)
+		}
+	}
+
+	if wire.UpdateP2PubKeyOrScriptHash.IsStandard() {
+		t.Fatal("UpdateP2PubKeyOrScriptHash should be non-standard")
+	}
+	if !wire.UpdateP2PubKeyOrScriptHash.IsValid() || wire.BloomUpdateType(4).IsValid() {
+		t.Fatal("only known policies should be valid")
+	}
+}
@@ -0,0 +1,129 @@
+package bloom_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// TestFilter_concurrent mixes readers and writers on the same filter, which
+// is expected to be run with the race detector enabled
+func TestFilter_concurrent(t *testing.T) {
+	const nWorker = 8
+
+	block := bip37.ReadBlock(t)
+
+	newFilter := func() *bloom.Filter {
+		f := bloom.New(10, 0.000001, wire.UpdateAll, bloom.Tweak)
+		h := block.Transactions[1].TxHash()
+		f.Add(h[:])
+		return f
+	}
+	replay := func(f *bloom.Filter) {
+		for _, tx := range block.Transactions {
+			f.MatchTxAndUpdate(btcutil.NewTx(tx))
+		}
+	}
+
+	// bits only grow, and more bits never match less, so any interleaving of
+	// the passes ends up between a single sequential pass and the fixed point
+	// of repeated ones
+	lower, upper := newFilter(), newFilter()
+	replay(lower)
+	for {
+		before := upper.Snapshot().Bits
+		replay(upper)
+		if bytes.Equal(before, upper.Snapshot().Bits) {
+			break
+		}
+	}
+
+	got := newFilter()
+
+	var wg sync.WaitGroup
+	for i := 0; i < nWorker; i++ {
+		wg.Add(2)
+
+		// the writer
+		go func() {
+			defer wg.Done()
+			replay(got)
+		}()
+
+		// the reader
+		go func() {
+			defer wg.Done()
+			for _, tx := range block.Transactions {
+				tx := btcutil.NewTx(tx)
+
+				got.Loaded()
+				got.Match(tx.Hash()[:])
+				got.MatchOutPoint(&tx.MsgTx().TxIn[0].PreviousOutPoint)
+				got.MatchTx(tx)
+				got.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	bits, min, max := got.Snapshot().Bits, lower.Snapshot().Bits,
+		upper.Snapshot().Bits
+	for i := range bits {
+		if bits[i]&min[i] != min[i] || bits[i]&max[i] != bits[i] {
+			t.Fatalf("invalid bits: got %x, expect between %x and %x", bits, min,
+				max)
+		}
+	}
+}
+
+func BenchmarkFilter_Match_parallel(b *testing.B) {
+	filter := bloom.New(1000, 0.0001, wire.UpdateAll, bloom.Tweak)
+
+	block := bip37.ReadBlock(b)
+	hashes := make([][]byte, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		h := tx.TxHash()
+		hashes[i] = h[:]
+		if i%2 == 0 {
+			filter.Add(hashes[i])
+		}
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			filter.Match(hashes[i%len(hashes)])
+			i++
+		}
+	})
+}
+
+func BenchmarkFilter_MatchTx_parallel(b *testing.B) {
+	filter := bloom.New(1000, 0.0001, wire.UpdateAll, bloom.Tweak)
+
+	block := bip37.ReadBlock(b)
+	txs := make([]*btcutil.Tx, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txs[i] = btcutil.NewTx(tx)
+		// warm up the cached hash, which isn't safe for concurrent use
+		txs[i].Hash()
+		if i%2 == 0 {
+			filter.Add(txs[i].Hash()[:])
+		}
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			filter.MatchTx(txs[i%len(txs)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package bloom
+
+import (
+	"math"
+	"sync"
+
+	"github.com/sammyne/bip37/wire"
+)
+
+// MaxCount is the saturated value of a counter, which would never be
+// decremented again since the real count is lost
+const MaxCount = math.MaxUint8
+
+// Counting implements a concurrent safe counting bloom filter, which keeps a
+// counter per bit slot of a standard filter and thus supports removal. It
+// hashes exactly as Filter, so it can be projected into a standard BIP37 bit
+// pattern for peers.
+type Counting struct {
+	mtx       sync.RWMutex
+	counters  []uint8
+	hashFuncs uint32
+	tweak     uint32
+	c         uint32
+	flags     wire.BloomUpdateType
+}
+
+// Add takes the given data into record by incrementing its counters
+func (f *Counting) Add(data []byte) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	for i := uint32(0); i < f.hashFuncs; i++ {
+		if j := f.hash(i, data); f.counters[j] < MaxCount {
+			f.counters[j]++
+		}
+	}
+}
+
+// Filter projects the counting filter into a standard one, which is
+// independent of the counting filter
+func (f *Counting) Filter() *Filter {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return &Filter{snapshot: f.snapshot(), c: f.c}
+}
+
+// Match checks if the data may be recorded by the filter
+func (f *Counting) Match(data []byte) bool {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	for i := uint32(0); i < f.hashFuncs; i++ {
+		if 0 == f.counters[f.hash(i, data)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Remove takes the given data out of record by decrementing its counters,
+// and returns ErrNotFound if the data is definitely not recorded. Removing
+// data never added may corrupt the filter by dropping other elements.
+func (f *Counting) Remove(data []byte) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	slots := make([]uint32, f.hashFuncs)
+	for i := range slots {
+		slots[i] = f.hash(uint32(i), data)
+		if 0 == f.counters[slots[i]] {
+			return ErrNotFound
+		}
+	}
+
+	for _, j := range slots {
+		// saturated counters have lost the real count, so they stay put
+		if f.counters[j] < MaxCount {
+			f.counters[j]--
+		}
+	}
+
+	return nil
+}
+
+// Snapshot projects the counting filter into the bit pattern of a standard
+// filter, where a bit is set iff its counter is non-zero
+func (f *Counting) Snapshot() *wire.FilterLoad {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return f.snapshot()
+}
+
+func (f *Counting) hash(idx uint32, data []byte) uint32 {
+	return hash(idx, f.c, f.tweak, uint32(len(f.counters)), data)
+}
+
+func (f *Counting) snapshot() *wire.FilterLoad {
+	bits := make([]byte, len(f.counters)>>3)
+	for i, v := range f.counters {
+		if 0 != v {
+			bits[i>>3] |= 1 << uint(i&0x07)
+		}
+	}
+
+	return &wire.FilterLoad{
+		Bits:      bits,
+		HashFuncs: f.hashFuncs,
+		Tweak:     f.tweak,
+		Flags:     f.flags,
+	}
+}
+
+// NewCounting makes a counting filter sized and parameterized exactly as New
+func NewCounting(N uint32, P float64, flags wire.BloomUpdateType,
+	tweaks ...uint32) *Counting {
+	S, nHashFuncs := calcSize(N, P)
+	tweak, c := parseTweaks(tweaks)
+
+	return &Counting{
+		counters:  make([]uint8, S<<3),
+		hashFuncs: nHashFuncs,
+		tweak:     tweak,
+		c:         c,
+		flags:     flags,
+	}
+}
@@ -0,0 +1,100 @@
+package bloom_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestCounting_Snapshot(t *testing.T) {
+	data := [][]byte{
+		bip37.Unhexlify("99108ad8ed9bb6274d3980bab5a85c048f0950c8"),
+		bip37.Unhexlify("b5a2c786d9ef4658287ced5914b37a1b4aa32eee"),
+		bip37.Unhexlify("b9300670b4c5366e95b2699e8b18bc75e5f729c5"),
+	}
+
+	// same as the case in TestNew_withTweak
+	expect := &wire.FilterLoad{
+		Bits:      bip37.Unhexlify("ce4299"),
+		HashFuncs: 5,
+		Tweak:     2147483649,
+		Flags:     wire.UpdateAll,
+	}
+
+	f := bloom.NewCounting(3, 0.01, wire.UpdateAll, 2147483649)
+	for _, v := range data {
+		f.Add(v)
+	}
+
+	if got := f.Snapshot(); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("invalid snapshot: got %v, expect %v", got, expect)
+	}
+
+	projected := f.Filter()
+	for i, v := range data {
+		if !projected.Match(v) {
+			t.Fatalf("#%d projected filter fails to match", i)
+		}
+	}
+}
+
+func TestCounting_Remove(t *testing.T) {
+	elements := fakeElements("real", 32)
+
+	f := bloom.NewCounting(uint32(len(elements)), 0.0001, wire.UpdateAll)
+	expect := bloom.New(uint32(len(elements)), 0.0001, wire.UpdateAll)
+	for i, v := range elements {
+		f.Add(v)
+		if i%2 == 0 {
+			expect.Add(v)
+		}
+	}
+
+	for i, v := range elements {
+		if i%2 == 0 {
+			continue
+		}
+
+		if err := f.Remove(v); nil != err {
+			t.Fatalf("#%d unexpected error: %v", i, err)
+		}
+	}
+
+	// removal restores the bit pattern of the remaining elements
+	if got := f.Snapshot(); !reflect.DeepEqual(got, expect.Snapshot()) {
+		t.Fatalf("invalid snapshot: got %v, expect %v", got, expect.Snapshot())
+	}
+
+	for i, v := range elements {
+		if i%2 == 0 && !f.Match(v) {
+			t.Fatalf("#%d remaining element is lost", i)
+		}
+	}
+}
+
+func TestCounting_Remove_notFound(t *testing.T) {
+	f := bloom.NewCounting(3, 0.01, wire.UpdateAll)
+
+	if err := f.Remove([]byte("hello world")); bloom.ErrNotFound != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, bloom.ErrNotFound)
+	}
+}
+
+func TestCounting_Remove_saturated(t *testing.T) {
+	data := []byte("hello world")
+
+	f := bloom.NewCounting(3, 0.01, wire.UpdateAll)
+	for i := 0; i < bloom.MaxCount+1; i++ {
+		f.Add(data)
+	}
+	for i := 0; i < bloom.MaxCount+1; i++ {
+		f.Remove(data)
+	}
+
+	if !f.Match(data) {
+		t.Fatal("saturated counters shouldn't be decremented")
+	}
+}
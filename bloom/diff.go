@@ -0,0 +1,105 @@
+package bloom
+
+import (
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37/wire"
+)
+
+// msgHeaderSize is the size of the header of every P2P message
+const msgHeaderSize = btcwire.MessageHeaderSize
+
+// Update is the minimal sequence of messages to bring a peer's filter from an
+// old element set to a new one. Exactly one of Adds and Load is used.
+type Update struct {
+	// Adds is the filteradd messages to send, if not reloading
+	Adds []*wire.FilterAdd
+	// Load is the fresh filterload to send, which is nil if not reloading
+	Load *wire.FilterLoad
+	// Bytes is the estimated bandwidth of the chosen messages
+	Bytes int
+	// FPRate is the estimated false positive rate of the peer's filter after
+	// applying the update
+	FPRate float64
+}
+
+// Diff decides how to bring a peer's filter, which is f built from the
+// element set before, to the element set after. Elements can only be removed by
+// reloading, and filteradd keeps degrading the FP rate of f, so a fresh
+// filterload with the parameters of f is chosen if
+//  - any element is removed, or
+//  - any added element exceeds MaxFilterAddSize, or
+//  - filteradds would push the FP rate beyond maxFPRate while reloading
+//    wouldn't, or
+//  - reloading costs less bandwidth than filteradds
+// Otherwise, the added elements are sent as filteradds in the order of after.
+func Diff(f *Filter, before, after [][]byte, maxFPRate float64) (*Update,
+	error) {
+	if !f.Loaded() {
+		return nil, ErrUninitialised
+	}
+
+	oldSet := make(map[string]bool, len(before))
+	for _, v := range before {
+		oldSet[string(v)] = true
+	}
+
+	var (
+		added   [][]byte
+		newSet  = make(map[string]bool, len(after))
+		reload  bool
+		addCost int
+	)
+	for _, v := range after {
+		if newSet[string(v)] {
+			continue
+		}
+		newSet[string(v)] = true
+
+		if oldSet[string(v)] {
+			continue
+		}
+
+		added = append(added, v)
+		addCost += msgHeaderSize + btcwire.VarIntSerializeSize(uint64(len(v))) +
+			len(v)
+		reload = reload || len(v) > MaxFilterAddSize
+	}
+
+	for k := range oldSet {
+		reload = reload || !newSet[k]
+	}
+
+	// the filter as if the additions are applied
+	patched := f.Clone()
+	for _, v := range added {
+		patched.Add(v)
+	}
+
+	// the fresh filter of the same parameters holding the new set only
+	snapshot, c := f.state()
+	snapshot.Bits = make([]byte, len(snapshot.Bits))
+	fresh := &Filter{snapshot: snapshot, c: c}
+	for k := range newSet {
+		fresh.Add([]byte(k))
+	}
+
+	load := fresh.Snapshot()
+	loadCost := msgHeaderSize + btcwire.VarIntSerializeSize(uint64(len(load.Bits))) +
+		len(load.Bits) + 9
+
+	patchedFPRate, freshFPRate := patched.EstimatedFPRate(), fresh.EstimatedFPRate()
+	if patchedFPRate > maxFPRate && freshFPRate <= maxFPRate {
+		reload = true
+	}
+
+	if reload || loadCost < addCost {
+		return &Update{Load: load, Bytes: loadCost, FPRate: freshFPRate}, nil
+	}
+
+	adds := make([]*wire.FilterAdd, len(added))
+	for i, v := range added {
+		adds[i] = &wire.FilterAdd{Data: append([]byte(nil), v...)}
+	}
+
+	return &Update{Adds: adds, Bytes: addCost, FPRate: patchedFPRate}, nil
+}
@@ -0,0 +1,139 @@
+package bloom_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// newDiffFilter makes a filter of capacity N holding the given elements
+func newDiffFilter(N uint32, elements [][]byte) *bloom.Filter {
+	f := bloom.New(N, 0.0001, wire.UpdateAll, bloom.Tweak)
+	for _, v := range elements {
+		f.Add(v)
+	}
+
+	return f
+}
+
+func TestDiff_adds(t *testing.T) {
+	before := fakeElements("wallet", 64)
+	after := append(append([][]byte{}, before...), fakeElements("new", 2)...)
+
+	update, err := bloom.Diff(newDiffFilter(128, before), before, after, 0.001)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if nil != update.Load {
+		t.Fatal("a few additions should be sent as filteradds")
+	}
+
+	if len(update.Adds) != 2 {
+		t.Fatalf("invalid #(filteradd): got %d, expect 2", len(update.Adds))
+	}
+	for i, v := range update.Adds {
+		if !bytes.Equal(v.Data, after[len(before)+i]) {
+			t.Fatalf("#%d invalid filteradd: got %x, expect %x", i, v.Data,
+				after[len(before)+i])
+		}
+	}
+}
+
+func TestDiff_load(t *testing.T) {
+	before := fakeElements("wallet", 8)
+
+	testCases := []struct {
+		desc  string
+		N     uint32
+		after [][]byte
+	}{
+		{"removal", 128, before[1:]},
+		{"oversized element", 128, append([][]byte{make([]byte, bloom.MaxFilterAddSize+1)}, before...)},
+		{"cheaper to reload", 8, append(append([][]byte{}, before...), fakeElements("new", 64)...)},
+	}
+
+	for i, c := range testCases {
+		f := newDiffFilter(c.N, before)
+
+		update, err := bloom.Diff(f, before, c.after, 1)
+		if nil != err {
+			t.Fatalf("#%d [%s] unexpected error: %v", i, c.desc, err)
+		}
+
+		if nil == update.Load || nil != update.Adds {
+			t.Fatalf("#%d [%s] reloading is expected", i, c.desc)
+		}
+
+		reloaded := bloom.Load(update.Load)
+		for j, v := range c.after {
+			if !reloaded.Match(v) {
+				t.Fatalf("#%d-%d [%s] reloaded filter fails to match", i, j, c.desc)
+			}
+		}
+
+		if snapshot := f.Snapshot(); len(update.Load.Bits) != len(snapshot.Bits) ||
+			update.Load.Tweak != snapshot.Tweak {
+			t.Fatalf("#%d [%s] reloaded filter should keep the parameters", i,
+				c.desc)
+		}
+	}
+}
+
+func TestDiff_fpDegradation(t *testing.T) {
+	before := fakeElements("wallet", 8)
+	f := newDiffFilter(64, before)
+
+	// pollute the filter as auto-added OutPoints do
+	for _, v := range fakeElements("outpoint", 128) {
+		f.Add(v)
+	}
+
+	after := append(append([][]byte{}, before...), fakeElements("new", 4)...)
+
+	update, err := bloom.Diff(f, before, after, 0.0001)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if nil == update.Load {
+		t.Fatalf("reloading is expected to restore the FP rate: %v", update)
+	}
+	if update.FPRate >= f.EstimatedFPRate() {
+		t.Fatalf("reloading should lower the FP rate: got %f, was %f",
+			update.FPRate, f.EstimatedFPRate())
+	}
+}
+
+func TestDiff_fpDegradation_beyondReload(t *testing.T) {
+	before := fakeElements("wallet", 8)
+	f := newDiffFilter(8, before)
+
+	for _, v := range fakeElements("outpoint", 128) {
+		f.Add(v)
+	}
+
+	after := append(append([][]byte{}, before...), fakeElements("new", 1)...)
+
+	// even a fresh filter exceeds such a threshold, so reloading buys nothing
+	// and the cheaper filteradd is kept
+	update, err := bloom.Diff(f, before, after, 1e-9)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if nil != update.Load || 1 != len(update.Adds) {
+		t.Fatalf("a single filteradd is expected: %v", update)
+	}
+}
+
+func TestDiff_cleared(t *testing.T) {
+	f := bloom.New(8, 0.01, wire.UpdateAll)
+	f.Clear()
+
+	if _, err := bloom.Diff(f, nil, nil, 1); bloom.ErrUninitialised != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, bloom.ErrUninitialised)
+	}
+}
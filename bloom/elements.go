@@ -0,0 +1,111 @@
+package bloom
+
+import (
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// OutputElements records the candidate elements of a tx output, i.e. the data
+// pushed by its public key script
+type OutputElements struct {
+	// Pushes is the data pushed by the public key script, which would be nil
+	// if the script fails to parse
+	Pushes [][]byte
+	// Class is the standard class of the public key script, which decides
+	// whether the OutPoint is updated under wire.UpdateP2PubKeyOnly
+	Class txscript.ScriptClass
+	// Taproot signals a P2TR output, whose only push is the x-only output
+	// key. txscript classifies it as non-standard.
+	Taproot bool
+}
+
+// InputElements records the candidate elements of a tx input
+type InputElements struct {
+	// OutPoint is the serialized previous OutPoint as `hash||index`
+	OutPoint []byte
+	// Pushes is the data pushed by the signature script, which would be nil
+	// if the script fails to parse
+	Pushes [][]byte
+	// PrevOutPushes is the data pushed by the public key script of the
+	// previous output, which is only resolved by ExtractElementsWithPrevOuts
+	PrevOutPushes [][]byte
+}
+
+// PrevOutFetcher resolves the public key script of a previous output, e.g.
+// from the UTXO set of a full node
+type PrevOutFetcher interface {
+	// FetchPrevOutScript returns the public key script of the output, and
+	// false if unknown
+	FetchPrevOutScript(out *btcwire.OutPoint) ([]byte, bool)
+}
+
+// TxElements collects all candidate elements of a tx to be checked by the
+// matching algorithm. Extracting them doesn't depend on any filter, so a
+// TxElements can be reused to match the same tx against many filters, where
+// only the Murmur3 hashing is done per filter
+type TxElements struct {
+	// Hash is the txid
+	Hash    []byte
+	Outputs []OutputElements
+	Inputs  []InputElements
+}
+
+// ExtractElements parses the scripts of tx and collects all its candidate
+// elements for matching in the order specified by BIP37
+func ExtractElements(tx *btcutil.Tx) *TxElements {
+	msg := tx.MsgTx()
+
+	elems := &TxElements{
+		Hash:    tx.Hash()[:],
+		Outputs: make([]OutputElements, len(msg.TxOut)),
+		Inputs:  make([]InputElements, len(msg.TxIn)),
+	}
+
+	for i, out := range msg.TxOut {
+		data, err := txscript.PushedData(out.PkScript)
+		if nil != err {
+			continue // leave the unexpected pushed data as nil
+		}
+
+		_, taproot := TaprootKey(out.PkScript)
+		elems.Outputs[i] = OutputElements{
+			Pushes:  data,
+			Class:   txscript.GetScriptClass(out.PkScript),
+			Taproot: taproot,
+		}
+	}
+
+	for i, in := range msg.TxIn {
+		elems.Inputs[i].OutPoint = marshalOutPoint(&in.PreviousOutPoint)
+
+		if data, err := txscript.PushedData(in.SignatureScript); nil == err {
+			elems.Inputs[i].Pushes = data
+		}
+	}
+
+	return elems
+}
+
+// ExtractElementsWithPrevOuts works as ExtractElements but also resolves the
+// public key script of each spent output by fetcher, so that spends can be
+// matched by the elements of the outputs they spend. This goes beyond BIP37,
+// which can't match spends with empty signature scripts, e.g. segwit ones,
+// without knowing their OutPoints.
+func ExtractElementsWithPrevOuts(tx *btcutil.Tx,
+	fetcher PrevOutFetcher) *TxElements {
+	elems := ExtractElements(tx)
+
+	for i, in := range tx.MsgTx().TxIn {
+		pkScript, ok := fetcher.FetchPrevOutScript(&in.PreviousOutPoint)
+		if !ok {
+			continue
+		}
+
+		if data, err := txscript.PushedData(pkScript); nil == err {
+			elems.Inputs[i].PrevOutPushes = data
+		}
+	}
+
+	return elems
+}
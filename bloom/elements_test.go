@@ -0,0 +1,142 @@
+package bloom_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestExtractElements(t *testing.T) {
+	block := bip37.ReadBlock(t)
+
+	tx := btcutil.NewTx(block.Transactions[2])
+	elems := bloom.ExtractElements(tx)
+
+	if !bytes.Equal(elems.Hash, tx.Hash()[:]) {
+		t.Fatalf("invalid tx hash: got %x, expect %x", elems.Hash, tx.Hash()[:])
+	}
+
+	if len(elems.Outputs) != len(tx.MsgTx().TxOut) {
+		t.Fatalf("invalid #(output): got %d, expect %d", len(elems.Outputs),
+			len(tx.MsgTx().TxOut))
+	}
+
+	expect := bip37.Unhexlify("fdacf9b3eb077412e7a968d2e4f11b9a9dee312d666187ed77ee7d26af16cb0b00000000")
+	if got := elems.Inputs[0].OutPoint; !bytes.Equal(got, expect) {
+		t.Fatalf("invalid OutPoint: got %x, expect %x", got, expect)
+	}
+}
+
+func TestFilter_MatchElementsAndUpdate(t *testing.T) {
+	block := bip37.ReadBlock(t)
+
+	flags := []wire.BloomUpdateType{
+		wire.UpdateNone, wire.UpdateAll, wire.UpdateP2PubKeyOnly,
+	}
+
+	for _, flag := range flags {
+		for i, tx := range block.Transactions {
+			// the filter matching tx by MatchTxAndUpdate
+			expect := bloom.New(10, 0.000001, flag, bloom.Tweak)
+			// the filter matching tx by MatchElementsAndUpdate
+			got := bloom.New(10, 0.000001, flag, bloom.Tweak)
+
+			// pre-add the elements of every other tx
+			for j, other := range block.Transactions {
+				if j%2 == i%2 {
+					continue
+				}
+
+				h := other.TxHash()
+				expect.Add(h[:])
+				got.Add(h[:])
+			}
+
+			elems := bloom.ExtractElements(btcutil.NewTx(tx))
+			for k, v := range elems.Outputs {
+				if 0 == k%2 && len(v.Pushes) > 0 {
+					expect.Add(v.Pushes[0])
+					got.Add(v.Pushes[0])
+				}
+			}
+
+			ok := expect.MatchTxAndUpdate(btcutil.NewTx(tx))
+			if v := got.MatchElementsAndUpdate(elems); v != ok {
+				t.Fatalf("%v #%d invalid matching status: got %v, expect %v", flag,
+					i, v, ok)
+			}
+
+			if x, y := got.Snapshot().Bits, expect.Snapshot().Bits; !bytes.Equal(x, y) {
+				t.Fatalf("%v #%d invalid bits: got %x, expect %x", flag, i, x, y)
+			}
+		}
+	}
+}
+
+func TestFilter_MatchElements(t *testing.T) {
+	block := bip37.ReadBlock(t)
+
+	filter := bloom.New(10, 0.000001, wire.UpdateAll, bloom.Tweak)
+	filter.Add(bip37.Unhexlify("1b8dd13b994bcfc787b32aeadf58ccb3615cbd54"))
+
+	expect := append([]byte{}, filter.Snapshot().Bits...)
+
+	if !filter.MatchElements(bloom.ExtractElements(btcutil.NewTx(block.Transactions[1]))) {
+		t.Fatal("matching is expected")
+	}
+
+	if got := filter.Snapshot().Bits; !bytes.Equal(got, expect) {
+		t.Fatalf("bits shouldn't be updated: got %x, expect %x", got, expect)
+	}
+}
+
+// newBenchFilters makes n filters of distinct tweaks, each of which records
+// the txid of one tx in block
+func newBenchFilters(b *testing.B, n int) []*bloom.Filter {
+	block := bip37.ReadBlock(b)
+
+	filters := make([]*bloom.Filter, n)
+	for i := range filters {
+		filters[i] = bloom.New(10, 0.000001, wire.UpdateAll, uint32(i))
+
+		h := block.Transactions[i%len(block.Transactions)].TxHash()
+		filters[i].Add(h[:])
+	}
+
+	return filters
+}
+
+func BenchmarkFilter_MatchTx(b *testing.B) {
+	const nFilter = 256
+
+	filters := newBenchFilters(b, nFilter)
+	block := bip37.ReadBlock(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx := btcutil.NewTx(block.Transactions[i%len(block.Transactions)])
+		for _, f := range filters {
+			f.MatchTx(tx)
+		}
+	}
+}
+
+func BenchmarkFilter_MatchElements(b *testing.B) {
+	const nFilter = 256
+
+	filters := newBenchFilters(b, nFilter)
+	block := bip37.ReadBlock(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx := btcutil.NewTx(block.Transactions[i%len(block.Transactions)])
+		elems := bloom.ExtractElements(tx)
+		for _, f := range filters {
+			f.MatchElements(elems)
+		}
+	}
+}
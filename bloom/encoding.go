@@ -1,11 +1,129 @@
 package bloom
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 
 	"github.com/btcsuite/btcd/wire"
+	bip37wire "github.com/sammyne/bip37/wire"
 )
 
+// filterJSON is the JSON representation of a filter, where C is optional and
+// defaults to the C constant as Load does
+type filterJSON struct {
+	Bits      string                    `json:"bits"`
+	HashFuncs uint32                    `json:"hashFuncs"`
+	Tweak     uint32                    `json:"tweak"`
+	Flags     bip37wire.BloomUpdateType `json:"flags"`
+	C         *uint32                   `json:"c,omitempty"`
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The output is the
+// payload of the filterload message followed by the C seed constant as a
+// little-endian uint32, i.e.
+//  bits (var bytes) || nHashFuncs (4) || nTweak (4) || nFlags (1) || C (4)
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	if nil == f.snapshot {
+		return nil, ErrUninitialised
+	}
+
+	var buf bytes.Buffer
+	// writing to bytes.Buffer never fails
+	wire.WriteVarBytes(&buf, 0, f.snapshot.Bits)
+
+	var tail [13]byte
+	binary.LittleEndian.PutUint32(tail[0:], f.snapshot.HashFuncs)
+	binary.LittleEndian.PutUint32(tail[4:], f.snapshot.Tweak)
+	tail[8] = byte(f.snapshot.Flags)
+	binary.LittleEndian.PutUint32(tail[9:], f.c)
+	buf.Write(tail[:])
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, which is the inverse
+// of MarshalBinary
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+
+	bits, err := wire.ReadVarBytes(r, 0, MaxFilterSize, "bits")
+	if nil != err {
+		return ErrInvalidEncoding
+	}
+
+	var tail [13]byte
+	if n, _ := r.Read(tail[:]); n != len(tail) || 0 != r.Len() {
+		return ErrInvalidEncoding
+	}
+
+	snapshot := &bip37wire.FilterLoad{
+		Bits:      bits,
+		HashFuncs: binary.LittleEndian.Uint32(tail[0:]),
+		Tweak:     binary.LittleEndian.Uint32(tail[4:]),
+		Flags:     bip37wire.BloomUpdateType(tail[8]),
+	}
+
+	return f.restore(snapshot, binary.LittleEndian.Uint32(tail[9:]))
+}
+
+// MarshalJSON implements json.Marshaler, where the bits is encoded as hex
+func (f *Filter) MarshalJSON() ([]byte, error) {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	if nil == f.snapshot {
+		return nil, ErrUninitialised
+	}
+
+	c := f.c
+	return json.Marshal(filterJSON{
+		Bits:      hex.EncodeToString(f.snapshot.Bits),
+		HashFuncs: f.snapshot.HashFuncs,
+		Tweak:     f.snapshot.Tweak,
+		Flags:     f.snapshot.Flags,
+		C:         &c,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, which is the inverse of
+// MarshalJSON. A missing "c" falls back to C, since filterload carries no C.
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	var v filterJSON
+	if err := json.Unmarshal(data, &v); nil != err {
+		return err
+	}
+
+	bits, err := hex.DecodeString(v.Bits)
+	if nil != err || len(bits) > MaxFilterSize {
+		return ErrInvalidEncoding
+	}
+
+	snapshot := &bip37wire.FilterLoad{
+		Bits:      bits,
+		HashFuncs: v.HashFuncs,
+		Tweak:     v.Tweak,
+		Flags:     v.Flags,
+	}
+
+	c := C
+	if nil != v.C {
+		c = *v.C
+	}
+
+	return f.restore(snapshot, c)
+}
+
+// OutPointElement returns the element of an OutPoint as added into filters
+// by AddOutPoint and MatchTxAndUpdate
+func OutPointElement(out *wire.OutPoint) []byte {
+	return marshalOutPoint(out)
+}
+
 // marshalOutPoint marshals a tx output interpreted as point as `hash||index`,
 // where the index is encoded in little-endian
 func marshalOutPoint(out *wire.OutPoint) []byte {
@@ -0,0 +1,154 @@
+package bloom_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestFilter_MarshalBinary(t *testing.T) {
+	const customC = 0x12345678
+
+	data := bip37.Unhexlify("99108ad8ed9bb6274d3980bab5a85c048f0950c8")
+
+	filter := bloom.New(3, 0.01, wire.UpdateP2PubKeyOnly, bloom.Tweak, customC)
+	filter.Add(data)
+
+	encoded, err := filter.MarshalBinary()
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	got := new(bloom.Filter)
+	if err := got.UnmarshalBinary(encoded); nil != err {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Snapshot(), filter.Snapshot()) {
+		t.Fatalf("invalid snapshot: got %v, expect %v", got.Snapshot(),
+			filter.Snapshot())
+	}
+
+	// the custom C must be restored to match the same data
+	if !got.Match(data) {
+		t.Fatal("failed to match data after decoding")
+	}
+
+	reencoded, _ := got.MarshalBinary()
+	if !reflect.DeepEqual(reencoded, encoded) {
+		t.Fatalf("invalid re-encoding: got %x, expect %x", reencoded, encoded)
+	}
+}
+
+func TestFilter_MarshalBinary_cleared(t *testing.T) {
+	filter := bloom.New(3, 0.01, wire.UpdateAll)
+	filter.Clear()
+
+	if _, err := filter.MarshalBinary(); bloom.ErrUninitialised != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, bloom.ErrUninitialised)
+	}
+}
+
+func TestFilter_UnmarshalBinary_errors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated bits", bip37.Unhexlify("0300")},
+		{"truncated tail", bip37.Unhexlify("01000300000005000000")},
+		{"trailing bytes", bip37.Unhexlify("0100030000000500000001ffffffff00")},
+		{"too many hash funcs", bip37.Unhexlify("0100ff0000000500000001ffffffff")},
+		{"hashing into empty bits", bip37.Unhexlify("00010000000500000001ffffffff")},
+		{"unknown flags", bip37.Unhexlify("01000300000005000000c8ffffffff")},
+	}
+
+	for i, c := range testCases {
+		if err := new(bloom.Filter).UnmarshalBinary(c.data); nil == err {
+			t.Fatalf("#%d [%s] should trigger error", i, c.desc)
+		}
+	}
+}
+
+func TestFilter_MarshalJSON(t *testing.T) {
+	const customC = 0x12345678
+
+	data := bip37.Unhexlify("b9300670b4c5366e95b2699e8b18bc75e5f729c5")
+
+	filter := bloom.New(3, 0.01, wire.UpdateAll, bloom.Tweak, customC)
+	filter.Add(data)
+
+	encoded, err := json.Marshal(filter)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	got := new(bloom.Filter)
+	if err := json.Unmarshal(encoded, got); nil != err {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Snapshot(), filter.Snapshot()) {
+		t.Fatalf("invalid snapshot: got %v, expect %v", got.Snapshot(),
+			filter.Snapshot())
+	}
+
+	if !got.Match(data) {
+		t.Fatal("failed to match data after decoding")
+	}
+}
+
+func TestFilter_UnmarshalJSON_defaultC(t *testing.T) {
+	data := bip37.Unhexlify("b9300670b4c5366e95b2699e8b18bc75e5f729c5")
+
+	filter := bloom.New(3, 0.01, wire.UpdateAll, bloom.Tweak)
+	filter.Add(data)
+
+	snapshot := filter.Snapshot()
+	encoded := fmt.Sprintf(`{"bits":"%x","hashFuncs":%d,"tweak":%d}`,
+		snapshot.Bits, snapshot.HashFuncs, snapshot.Tweak)
+
+	got := new(bloom.Filter)
+	if err := json.Unmarshal([]byte(encoded), got); nil != err {
+		t.Fatal(err)
+	}
+
+	expect := bloom.Load(&wire.FilterLoad{
+		Bits:      snapshot.Bits,
+		HashFuncs: snapshot.HashFuncs,
+		Tweak:     snapshot.Tweak,
+	})
+	for i, v := range fakeElements("probe", 256) {
+		if got.Match(v) != expect.Match(v) {
+			t.Fatalf("#%d %x: matching status differs from bloom.Load", i, v)
+		}
+	}
+
+	if !got.Match(data) {
+		t.Fatal("failed to match data after decoding")
+	}
+}
+
+func TestFilter_UnmarshalJSON_errors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		data string
+	}{
+		{"not an object", `[]`},
+		{"bad hex", `{"bits":"xyz","hashFuncs":1}`},
+		{"too many hash funcs", `{"bits":"00","hashFuncs":51}`},
+		{"hashing into empty bits", `{"bits":"","hashFuncs":1}`},
+		{"unknown flags", `{"bits":"00","hashFuncs":1,"flags":200}`},
+	}
+
+	for i, c := range testCases {
+		if err := json.Unmarshal([]byte(c.data), new(bloom.Filter)); nil == err {
+			t.Fatalf("#%d [%s] should trigger error", i, c.desc)
+		}
+	}
+}
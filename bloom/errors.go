@@ -4,3 +4,21 @@ import "errors"
 
 // ErrUninitialised signals the filter isn't initialized properly
 var ErrUninitialised = errors.New("filter isn't initialised yet")
+
+// ErrInvalidEncoding signals the encoded filter is malformed or violates
+// the limits of BIP37
+var ErrInvalidEncoding = errors.New("invalid filter encoding")
+
+// ErrInvalidPrivacy signals the privacy settings can't be fulfilled by the
+// given elements
+var ErrInvalidPrivacy = errors.New("invalid privacy settings")
+
+// ErrNotFound signals the element is definitely not recorded by the filter
+var ErrNotFound = errors.New("element not found")
+
+// ErrIncompatible signals the filters differ in size, HashFuncs, Tweak or C
+var ErrIncompatible = errors.New("incompatible filters")
+
+// ErrInvalidTaprootKey signals the key is neither x-only nor compressed, or
+// fails to tweak
+var ErrInvalidTaprootKey = errors.New("invalid taproot key")
@@ -9,9 +9,11 @@ import (
 
 var ln2Sqr = math.Ln2 * math.Ln2
 
-// Filter implements a concurrent safe bloom filter
+// Filter implements a concurrent safe bloom filter. Matching without update
+// only takes the read lock, so that read-heavy workloads from many goroutines
+// can proceed in parallel
 type Filter struct {
-	mtx      sync.Mutex
+	mtx      sync.RWMutex
 	snapshot *wire.FilterLoad
 	c        uint32
 }
@@ -33,11 +35,36 @@ func (f *Filter) Clear() {
 	f.snapshot = nil
 }
 
+// EstimatedFPRate estimates the false positive rate of filter from its
+// current fill as FillRatio^HashFuncs, which is safe for concurrent use. An
+// empty bit pattern matches everything, thus has a rate of 1.
+func (f *Filter) EstimatedFPRate() float64 {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	if nil == f.snapshot {
+		return 0
+	} else if 0 == len(f.snapshot.Bits) {
+		return 1
+	}
+
+	return math.Pow(f.fillRatio(), float64(f.snapshot.HashFuncs))
+}
+
+// FillRatio calculates the proportion of set bits in the bit pattern, which
+// is safe for concurrent use
+func (f *Filter) FillRatio() float64 {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return f.fillRatio()
+}
+
 // Loaded checks if the filter has been initialized properly, which is safe for
 // concurrent use
 func (f *Filter) Loaded() bool {
-	f.mtx.Lock()
-	defer f.mtx.Unlock()
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
 
 	return nil != f.snapshot
 }
@@ -45,28 +72,44 @@ func (f *Filter) Loaded() bool {
 // Match checks if the data may be recorded by the filter, which is safe for
 // concurrent use
 func (f *Filter) Match(data []byte) bool {
-	f.mtx.Lock()
-	defer f.mtx.Unlock()
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
 
 	return f.match(data)
 }
 
-// Recover overrides the bit pattern in a concurrently safe manner
+// Clone makes a deep copy of the filter, which shares no state with the
+// original one and is safe for concurrent use
+func (f *Filter) Clone() *Filter {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return &Filter{snapshot: copySnapshot(f.snapshot), c: f.c}
+}
+
+// Recover overrides the bit pattern in a concurrently safe manner. The
+// snapshot is copied, so later updates to the filter won't affect it. Since
+// the wire.FilterLoad carries no C seed constant, a filter without one yet
+// falls back to the default C
 func (f *Filter) Recover(snapshot *wire.FilterLoad) *Filter {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
 
-	f.snapshot = snapshot
+	f.snapshot = copySnapshot(snapshot)
+	if 0 == f.c {
+		f.c = C
+	}
 
 	return f
 }
 
-// Snapshot return the bit pattern maintained by filter up till now
+// Snapshot return a copy of the bit pattern maintained by filter up till now,
+// which is safe to send or share while the filter keeps updating
 func (f *Filter) Snapshot() *wire.FilterLoad {
-	f.mtx.Lock()
-	defer f.mtx.Unlock()
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
 
-	return f.snapshot
+	return copySnapshot(f.snapshot)
 }
 
 // Load is the procedural version of Filter.Recover
@@ -74,30 +117,30 @@ func Load(snapshot *wire.FilterLoad) *Filter {
 	return new(Filter).Recover(snapshot)
 }
 
+// Validate checks the snapshot against the limits of BIP37, and rejects
+// hashing into an empty bit pattern or an unknown updating policy with
+// ErrInvalidEncoding. Non-standard policies are deemed valid.
+func Validate(snapshot *wire.FilterLoad) error {
+	switch {
+	case len(snapshot.Bits) > MaxFilterSize, snapshot.HashFuncs > MaxHashFuncs:
+		return ErrInvalidEncoding
+	case 0 == len(snapshot.Bits) && snapshot.HashFuncs > 0:
+		return ErrInvalidEncoding
+	case !snapshot.Flags.IsValid():
+		return ErrInvalidEncoding
+	}
+
+	return nil
+}
+
 // New serves as the constructor of a bloom filter according to
 // specification in https://github.com/bitcoin/bips/blob/master/bip-0037.mediawiki#bloom-filter-format
+// The tweak falls back to the fixed Tweak if not given, which makes filters
+// easy to fingerprint, so NewRandom is recommended instead
 func New(N uint32, P float64, flags wire.BloomUpdateType,
 	tweaks ...uint32) *Filter {
-	// false positive rate
-	P = math.Max(1e-9, math.Min(P, 1))
-
-	// calculates size of the filter S = -1/ln2Sqr*N*ln(P)/8
-	S := uint32(-1 / ln2Sqr * float64(N) * math.Log(P) / 8)
-	// normalize S to range (0, MaxFilterSize]
-	S = MinUint32(S, MaxFilterSize)
-
-	// calculates the nHashFuncs = S*8/N*ln2
-	nHashFuncs := uint32(float64(S*8) / float64(N) * math.Ln2)
-	// normalize nHashFuncs to range (0, MaxHashFuncs)
-	nHashFuncs = MinUint32(nHashFuncs, MaxHashFuncs)
-
-	c, tweak := C, Tweak
-	if len(tweaks) >= 1 {
-		tweak = tweaks[0]
-	}
-	if len(tweaks) >= 2 {
-		c = tweaks[1]
-	}
+	S, nHashFuncs := calcSize(N, P)
+	tweak, c := parseTweaks(tweaks)
 
 	return &Filter{
 		snapshot: &wire.FilterLoad{
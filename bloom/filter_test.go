@@ -96,12 +96,106 @@ func TestFilter_Clear(t *testing.T) {
 	}
 }
 
+func TestFilter_FillRatio(t *testing.T) {
+	filter := bloom.Load(&wire.FilterLoad{
+		Bits:      bip37.Unhexlify("ff0f0000"),
+		HashFuncs: 2,
+	})
+
+	if got := filter.FillRatio(); got != 0.375 {
+		t.Fatalf("invalid fill ratio: got %f, expect %f", got, 0.375)
+	}
+	if got := filter.EstimatedFPRate(); got != 0.375*0.375 {
+		t.Fatalf("invalid FP rate: got %f, expect %f", got, 0.375*0.375)
+	}
+
+	filter.Clear()
+	if got := filter.EstimatedFPRate(); got != 0 {
+		t.Fatalf("cleared filter should have FP rate 0: got %f", got)
+	}
+}
+
 func TestFilter_Load(t *testing.T) {
 	expect := &wire.FilterLoad{Bits: []byte("hello world")}
 
-	if got := bloom.Load(expect).Snapshot(); got != expect {
+	filter := bloom.Load(expect)
+	if got := filter.Snapshot(); !reflect.DeepEqual(got, expect) {
 		t.Fatal("failed to load filter from snapshot")
 	}
+
+	// the loaded filter shouldn't alias the given snapshot
+	expect.Bits[0] = 'H'
+	if got := filter.Snapshot(); got.Bits[0] != 'h' {
+		t.Fatal("loaded filter shares bits with the snapshot")
+	}
+}
+
+func TestFilter_Clone(t *testing.T) {
+	data := [][]byte{
+		bip37.Unhexlify("99108ad8ed9bb6274d3980bab5a85c048f0950c8"),
+		bip37.Unhexlify("b9300670b4c5366e95b2699e8b18bc75e5f729c5"),
+	}
+
+	template := bloom.New(3, 0.01, wire.UpdateAll, bloom.Tweak, bloom.C)
+	template.Add(data[0])
+
+	clone := template.Clone()
+	if !reflect.DeepEqual(clone.Snapshot(), template.Snapshot()) {
+		t.Fatal("clone should be identical to the template")
+	}
+
+	clone.Add(data[1])
+	if template.Match(data[1]) {
+		t.Fatal("the template is mutated by the clone")
+	}
+	if !clone.Match(data[0]) || !clone.Match(data[1]) {
+		t.Fatal("the clone fails to match the added data")
+	}
+}
+
+func TestFilter_Clone_cleared(t *testing.T) {
+	filter := bloom.New(3, 0.01, wire.UpdateAll)
+	filter.Clear()
+
+	if filter.Clone().Loaded() {
+		t.Fatal("clone of a cleared filter shouldn't be loaded")
+	}
+}
+
+func TestFilter_Load_defaultC(t *testing.T) {
+	data := bip37.Unhexlify("99108ad8ed9bb6274d3980bab5a85c048f0950c8")
+
+	filter := bloom.New(3, 0.01, wire.UpdateAll, bloom.Tweak)
+	filter.Add(data)
+
+	if !bloom.Load(filter.Snapshot()).Match(data) {
+		t.Fatal("loaded filter should hash with the default C")
+	}
+}
+
+// TestFilter_emptyBits covers filters of empty bits but non-zero hash funcs
+// sent by peers, which must match everything rather than dividing by zero
+func TestFilter_emptyBits(t *testing.T) {
+	filter := bloom.Load(&wire.FilterLoad{Bits: nil, HashFuncs: 1})
+
+	if err := filter.Add([]byte("hello")); nil != err {
+		t.Fatal(err)
+	}
+
+	if !filter.Match([]byte("world")) {
+		t.Fatal("empty bits should match everything")
+	}
+
+	block := bip37.ReadBlock(t)
+	for i, tx := range block.Transactions {
+		if !filter.MatchTxAndUpdate(btcutil.NewTx(tx)) {
+			t.Fatalf("#%d empty bits should match every tx", i)
+		}
+	}
+
+	if got := filter.EstimatedFPRate(); 1 != got {
+		t.Fatalf("invalid FP rate: got %v, expect 1", got)
+	}
 }
 
 func TestFilter_Loaded(t *testing.T) {
@@ -165,11 +259,25 @@ func TestFilter_Recover(t *testing.T) {
 
 	filter := new(bloom.Filter).Recover(snapshot)
 
-	if got := filter.Snapshot(); got != snapshot {
+	if got := filter.Snapshot(); !reflect.DeepEqual(got, snapshot) {
 		t.Fatal("snapshot isn't recovered correctly")
 	}
 }
 
+func TestFilter_Snapshot(t *testing.T) {
+	filter := bloom.New(3, 0.01, wire.UpdateAll, bloom.Tweak, bloom.C)
+
+	snapshot := filter.Snapshot()
+	expect := append([]byte{}, snapshot.Bits...)
+
+	filter.Add(bip37.Unhexlify("99108ad8ed9bb6274d3980bab5a85c048f0950c8"))
+
+	if !bytes.Equal(snapshot.Bits, expect) {
+		t.Fatalf("snapshot is mutated by later Add: got %x, expect %x",
+			snapshot.Bits, expect)
+	}
+}
+
 func TestNew(t *testing.T) {
 	type expect struct {
 		bitsLen    int
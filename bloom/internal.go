@@ -1,6 +1,10 @@
 package bloom
 
 import (
+	"math"
+	"math/bits"
+
+	"github.com/sammyne/bip37/wire"
 	"github.com/sammyne/murmur3"
 )
 
@@ -8,6 +12,8 @@ import (
 func (f *Filter) add(data []byte) error {
 	if nil == f.snapshot {
 		return ErrUninitialised
+	} else if 0 == len(f.snapshot.Bits) {
+		return nil // nothing to hash into, as Bitcoin Core does
 	}
 
 	for i := uint32(0); i < f.snapshot.HashFuncs; i++ {
@@ -20,21 +26,45 @@ func (f *Filter) add(data []byte) error {
 	return nil
 }
 
+// fillRatio calculates the proportion of set bits in the bit pattern, where
+// an uninitialised or empty filter is deemed as empty
+func (f *Filter) fillRatio() float64 {
+	if nil == f.snapshot || 0 == len(f.snapshot.Bits) {
+		return 0
+	}
+
+	var ones int
+	for _, b := range f.snapshot.Bits {
+		ones += bits.OnesCount8(b)
+	}
+
+	return float64(ones) / float64(len(f.snapshot.Bits)<<3)
+}
+
 // hash estimates the bit index mapped from the given data for the i-th
 // Murmur3 employed by the filter. `idx` is used to differentiate the
 // seed for each Murmur3 hashing, where the seed used by the i-th Murmur3
 // would be `idx*C + Tweak`
 func (f *Filter) hash(idx uint32, data []byte) uint32 {
-	// seed = idx*C + f.snapshot.Tweak
-	bitIdx := murmur3.SumUint32(data, idx*f.c+f.snapshot.Tweak)
-	//fmt.Printf("%d-%d-%d-%x: %d\n", idx, f.c, f.snapshot.Tweak, data, bitIdx)
-	return bitIdx % (uint32(len(f.snapshot.Bits)) << 3)
+	return hash(idx, f.c, f.snapshot.Tweak, uint32(len(f.snapshot.Bits))<<3,
+		data)
+}
+
+// hash maps data to a slot index within nSlots for the idx-th Murmur3 of a
+// filter parameterized by c and tweak
+func hash(idx, c, tweak, nSlots uint32, data []byte) uint32 {
+	// seed = idx*C + tweak
+	return murmur3.SumUint32(data, idx*c+tweak) % nSlots
 }
 
 // match checks if the given data pattern is possibly recorded by the filter
 func (f *Filter) match(data []byte) bool {
 	if nil == f.snapshot {
 		return false
+	} else if 0 == len(f.snapshot.Bits) {
+		// an empty bit pattern matches everything as Bitcoin Core does, which
+		// also avoids dividing by zero in hash
+		return true
 	}
 
 	// iterating each hash output and ensure the corresponding is set
@@ -48,3 +78,68 @@ func (f *Filter) match(data []byte) bool {
 
 	return true
 }
+
+// restore overrides the whole state of filter with the given snapshot and
+// C seed constant after validating them by Validate
+func (f *Filter) restore(snapshot *wire.FilterLoad, c uint32) error {
+	if err := Validate(snapshot); nil != err {
+		return err
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.snapshot, f.c = snapshot, c
+
+	return nil
+}
+
+// calcSize estimates the size in bytes of the bit pattern and the number of
+// hash functions for a filter holding N elements at false positive rate P
+func calcSize(N uint32, P float64) (uint32, uint32) {
+	P = clampFPRate(P)
+
+	// calculates size of the filter S = -1/ln2Sqr*N*ln(P)/8
+	S := uint32(-1 / ln2Sqr * float64(N) * math.Log(P) / 8)
+	// normalize S to range (0, MaxFilterSize]
+	S = MinUint32(S, MaxFilterSize)
+
+	// calculates the nHashFuncs = S*8/N*ln2
+	nHashFuncs := uint32(float64(S*8) / float64(N) * math.Ln2)
+	// normalize nHashFuncs to range (0, MaxHashFuncs)
+	nHashFuncs = MinUint32(nHashFuncs, MaxHashFuncs)
+
+	return S, nHashFuncs
+}
+
+// clampFPRate normalizes the false positive rate P into [1e-9, 1]
+func clampFPRate(P float64) float64 {
+	return math.Max(1e-9, math.Min(P, 1))
+}
+
+// parseTweaks picks the tweak and C seed constant from the optional tweaks
+// accepted by constructors, which default to Tweak and C respectively
+func parseTweaks(tweaks []uint32) (uint32, uint32) {
+	tweak, c := Tweak, C
+	if len(tweaks) >= 1 {
+		tweak = tweaks[0]
+	}
+	if len(tweaks) >= 2 {
+		c = tweaks[1]
+	}
+
+	return tweak, c
+}
+
+// copySnapshot makes a deep copy of the given snapshot, where nil is copied
+// as nil
+func copySnapshot(snapshot *wire.FilterLoad) *wire.FilterLoad {
+	if nil == snapshot {
+		return nil
+	}
+
+	out := *snapshot
+	out.Bits = append([]byte(nil), snapshot.Bits...)
+
+	return &out
+}
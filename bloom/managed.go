@@ -0,0 +1,194 @@
+package bloom
+
+import (
+	"io"
+	"sync"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/wire"
+)
+
+// DefaultGrowth is the default factor to grow the capacity of a managed
+// filter by upon rebuilding
+const DefaultGrowth = 2
+
+// ManagedConfig configures a managed filter
+type ManagedConfig struct {
+	// N is the initial number of elements the filter is sized for
+	N uint32
+	// P is the targeted false positive rate
+	P float64
+	// Flags is the updating policy of the filter
+	Flags wire.BloomUpdateType
+	// MaxFPRate is the threshold of the estimated FP rate, beyond which the
+	// filter is rebuilt. It defaults to 10*P if not positive, where P is
+	// clamped into [1e-9, 1] as New does
+	MaxFPRate float64
+	// Growth is the factor to grow the capacity by upon rebuilding, which
+	// defaults to DefaultGrowth if not greater than 1
+	Growth float64
+	// Rand is the source of tweaks, which defaults to crypto/rand.Reader if
+	// nil
+	Rand io.Reader
+}
+
+// Managed wraps a filter and keeps track of every element in it, including
+// OutPoints added by MatchTxAndUpdate. Once the estimated FP rate of the
+// filter crosses the threshold, it is rebuilt from the tracked elements with
+// a fresh tweak and a larger capacity, and a new filterload is emitted for the
+// client to send. It is safe for concurrent use.
+type Managed struct {
+	mtx sync.Mutex
+
+	config   ManagedConfig
+	filter   *Filter
+	capacity uint32
+	// elements is the tracked element set keyed by its string form
+	elements map[string][]byte
+	// saturated signals the filter has reached MaxFilterSize, thus can't be
+	// improved by rebuilding any more
+	saturated bool
+}
+
+// NewManaged makes a managed filter holding the given elements
+func NewManaged(config ManagedConfig, elements ...[]byte) (*Managed, error) {
+	if config.MaxFPRate <= 0 {
+		config.MaxFPRate = 10 * clampFPRate(config.P)
+	}
+	if config.Growth <= 1 {
+		config.Growth = DefaultGrowth
+	}
+
+	m := &Managed{
+		config:   config,
+		capacity: config.N,
+		elements: make(map[string][]byte),
+	}
+	for _, v := range elements {
+		m.elements[string(v)] = append([]byte(nil), v...)
+	}
+
+	if _, err := m.rebuild(); nil != err {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Add takes data into the filter, and returns the new filterload if the
+// filter is rebuilt consequently
+func (m *Managed) Add(data []byte) (*wire.FilterLoad, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.elements[string(data)] = append([]byte(nil), data...)
+	if err := m.filter.Add(data); nil != err {
+		return nil, err
+	}
+
+	return m.rebuildIfSaturated()
+}
+
+// Elements returns a copy of the tracked elements in arbitrary order
+func (m *Managed) Elements() [][]byte {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	out := make([][]byte, 0, len(m.elements))
+	for _, v := range m.elements {
+		out = append(out, append([]byte(nil), v...))
+	}
+
+	return out
+}
+
+// Filter returns the current filter, which shouldn't be updated directly
+// otherwise the added elements won't be tracked
+func (m *Managed) Filter() *Filter {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.filter
+}
+
+// Match checks if the data may be recorded by the filter
+func (m *Managed) Match(data []byte) bool {
+	return m.Filter().Match(data)
+}
+
+// MatchTxAndUpdate checks if the tx matches the filter and updates it
+// accordingly. The new filterload is returned if the filter is rebuilt due to
+// crossing the threshold.
+func (m *Managed) MatchTxAndUpdate(tx *btcutil.Tx) (bool, *wire.FilterLoad,
+	error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	ok, tracked := m.filter.MatchTxAndTrack(tx)
+	for i := range tracked {
+		v := marshalOutPoint(&tracked[i])
+		m.elements[string(v)] = v
+	}
+
+	if 0 == len(tracked) {
+		return ok, nil, nil
+	}
+
+	reload, err := m.rebuildIfSaturated()
+
+	return ok, reload, err
+}
+
+// Snapshot returns the filterload of the current filter
+func (m *Managed) Snapshot() *wire.FilterLoad {
+	return m.Filter().Snapshot()
+}
+
+// TrackOutPoint takes the OutPoint into the filter as AddOutPoint, and
+// returns the new filterload if the filter is rebuilt consequently
+func (m *Managed) TrackOutPoint(out *btcwire.OutPoint) (*wire.FilterLoad,
+	error) {
+	return m.Add(marshalOutPoint(out))
+}
+
+// rebuild makes a new filter from the tracked elements with a fresh tweak,
+// which is sized for the larger one of capacity and #(elements)
+func (m *Managed) rebuild() (*wire.FilterLoad, error) {
+	if n := uint32(len(m.elements)); n > m.capacity {
+		m.capacity = n
+	}
+	if 0 == m.capacity {
+		m.capacity = 1
+	}
+
+	filter, err := NewRandom(m.capacity, m.config.P, m.config.Flags,
+		m.config.Rand)
+	if nil != err {
+		return nil, err
+	}
+
+	for _, v := range m.elements {
+		filter.Add(v)
+	}
+
+	m.filter = filter
+	m.saturated = MaxFilterSize == len(filter.snapshot.Bits)
+
+	return filter.Snapshot(), nil
+}
+
+// rebuildIfSaturated rebuilds the filter with a grown capacity if its
+// estimated FP rate crosses the threshold, and returns nil if not rebuilt
+func (m *Managed) rebuildIfSaturated() (*wire.FilterLoad, error) {
+	if m.saturated || m.filter.EstimatedFPRate() <= m.config.MaxFPRate {
+		return nil, nil
+	}
+
+	if n := uint32(len(m.elements)); n > m.capacity {
+		m.capacity = n
+	}
+	m.capacity = uint32(float64(m.capacity) * m.config.Growth)
+
+	return m.rebuild()
+}
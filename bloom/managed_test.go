@@ -0,0 +1,144 @@
+package bloom_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestManaged_Add(t *testing.T) {
+	elements := fakeElements("real", 64)
+
+	m, err := bloom.NewManaged(bloom.ManagedConfig{
+		N:     8,
+		P:     0.001,
+		Flags: wire.UpdateAll,
+	}, elements[:8]...)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	before := m.Snapshot()
+
+	var reloads []*wire.FilterLoad
+	for _, v := range elements[8:] {
+		reload, err := m.Add(v)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		if nil != reload {
+			reloads = append(reloads, reload)
+		}
+	}
+
+	if 0 == len(reloads) {
+		t.Fatal("filter should be rebuilt after exceeding its capacity")
+	}
+
+	last := reloads[len(reloads)-1]
+	if len(last.Bits) <= len(before.Bits) {
+		t.Fatalf("rebuilt filter should grow: got %d bytes, was %d bytes",
+			len(last.Bits), len(before.Bits))
+	}
+	if current := m.Snapshot(); last.Tweak != current.Tweak ||
+		len(last.Bits) != len(current.Bits) {
+		t.Fatal("the last reload should be the current filter")
+	}
+
+	for i, v := range elements {
+		if !m.Match(v) {
+			t.Fatalf("#%d tracked element is lost after rebuilding", i)
+		}
+	}
+
+	if fpRate := m.Filter().EstimatedFPRate(); fpRate > 0.01 {
+		t.Fatalf("FP rate should be kept under the threshold: got %f", fpRate)
+	}
+}
+
+// TestManaged_Add_zeroP checks the default threshold follows the clamped P,
+// otherwise every addition would trigger a rebuild
+func TestManaged_Add_zeroP(t *testing.T) {
+	m, err := bloom.NewManaged(bloom.ManagedConfig{N: 8, Flags: wire.UpdateAll})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	for i, v := range fakeElements("real", 4) {
+		reload, err := m.Add(v)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		if nil != reload {
+			t.Fatalf("#%d unexpected rebuild within capacity", i)
+		}
+	}
+}
+
+func TestManaged_MatchTxAndUpdate(t *testing.T) {
+	block := bip37.ReadBlock(t)
+
+	// matching every tx hash makes the filter add all OutPoints
+	var hashes [][]byte
+	for _, tx := range block.Transactions {
+		h := tx.TxHash()
+		hashes = append(hashes, h[:])
+	}
+
+	// elements pushed by outputs keep adding OutPoints under UpdateAll
+	var pushes [][]byte
+	for _, tx := range block.Transactions {
+		elems := bloom.ExtractElements(btcutil.NewTx(tx))
+		for _, out := range elems.Outputs {
+			pushes = append(pushes, out.Pushes...)
+		}
+	}
+
+	m, err := bloom.NewManaged(bloom.ManagedConfig{
+		N:         uint32(len(pushes)),
+		P:         0.0001,
+		Flags:     wire.UpdateAll,
+		MaxFPRate: 0.0002,
+	}, pushes...)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var nReload int
+	for _, tx := range block.Transactions {
+		tx := btcutil.NewTx(tx)
+
+		ok, reload, err := m.MatchTxAndUpdate(tx)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("tx %s should be matched", tx.Hash())
+		}
+
+		if nil != reload {
+			nReload++
+		}
+
+		// the OutPoints of tx must survive any rebuilding
+		for i := range tx.MsgTx().TxOut {
+			if !m.Filter().MatchOutPoint(bip37.NewOutPoint(tx.Hash()[:], uint32(i))) {
+				t.Fatalf("OutPoint %s:%d isn't tracked", tx.Hash(), i)
+			}
+		}
+	}
+
+	if 0 == nReload {
+		t.Fatal("filter should be rebuilt after tracking all OutPoints")
+	}
+
+	if got, expect := len(m.Elements()), len(pushes); got <= expect {
+		t.Fatalf("OutPoints aren't tracked: got %d elements, expect > %d", got,
+			expect)
+	}
+}
@@ -0,0 +1,56 @@
+package bloom_test
+
+import (
+	"testing"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// prevOuts is an in-memory PrevOutFetcher
+type prevOuts map[btcwire.OutPoint][]byte
+
+func (p prevOuts) FetchPrevOutScript(out *btcwire.OutPoint) ([]byte, bool) {
+	pkScript, ok := p[*out]
+	return pkScript, ok
+}
+
+func TestFilter_MatchTxWithPrevOuts(t *testing.T) {
+	owned := bip37.Unhexlify("1b8dd13b994bcfc787b32aeadf58ccb3615cbd54")
+	prevOut := bip37.NewOutPoint(
+		bip37.Unhexlify("2440cddbc2a189357e7bc0e4f552bfde79e7f6b16d41c32436a67fd6db8f5051"), 1)
+
+	// the segwit spend carries nothing but the witness
+	msg := btcwire.NewMsgTx(btcwire.TxVersion)
+	msg.AddTxIn(btcwire.NewTxIn(prevOut, nil, [][]byte{{0x30}, {0x02}}))
+	msg.AddTxOut(btcwire.NewTxOut(1000, append([]byte{0x00, 0x14}, make([]byte, 20)...)))
+	tx := btcutil.NewTx(msg)
+
+	fetcher := prevOuts{*prevOut: append([]byte{0x00, 0x14}, owned...)}
+
+	filter := bloom.New(10, 0.000001, wire.UpdateAll, bloom.Tweak)
+	filter.Add(owned)
+
+	if filter.MatchTx(tx) {
+		t.Fatal("BIP37 matching shouldn't see the spend")
+	}
+
+	if !filter.MatchTxWithPrevOuts(tx, fetcher) {
+		t.Fatal("the spend should be matched by the previous output")
+	}
+
+	if filter.MatchTxWithPrevOuts(tx, prevOuts{}) {
+		t.Fatal("unknown previous outputs shouldn't be matched")
+	}
+
+	before := filter.Snapshot()
+	if !filter.MatchTxAndUpdateWithPrevOuts(tx, fetcher) {
+		t.Fatal("the spend should be matched by the previous output")
+	}
+	if got := filter.Snapshot(); string(got.Bits) != string(before.Bits) {
+		t.Fatal("matching by inputs shouldn't update the filter")
+	}
+}
@@ -0,0 +1,138 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/sammyne/bip37/wire"
+)
+
+// DecoySize is the length of each random decoy element, which mimics a
+// HASH160 so that decoys look like ordinary address elements
+const DecoySize = 20
+
+// PrivacyLevel is the expected number of innocent elements an observer would
+// confuse with each real element of a wallet
+type PrivacyLevel uint32
+
+// Enumerations of recommended privacy levels
+const (
+	PrivacyLow    PrivacyLevel = 10
+	PrivacyMedium PrivacyLevel = 100
+	PrivacyHigh   PrivacyLevel = 1000
+)
+
+// PrivateBuilder builds filters which hide the real elements of a wallet among
+// false positives. An observer testing Universe candidate elements against the
+// filter is expected to match about Level innocent ones per real element.
+// Random decoys are added, whose number is drawn uniformly from
+// [#(real), 3*#(real)], and the filter is sized for the padded number of
+// elements. So neither the size nor the fill of the filter tells more than
+// that the number of real elements lies within [1/4, 1/2] of the padded one.
+type PrivateBuilder struct {
+	// Level is the targeted privacy level
+	Level PrivacyLevel
+	// Universe is the estimated number of candidate elements the observer
+	// would test against the filter, e.g. the number of addresses on chain
+	Universe uint64
+	// Flags is the updating policy of the built filter
+	Flags wire.BloomUpdateType
+	// Rand is the source of the tweak and decoys, which defaults to
+	// crypto/rand.Reader if nil
+	Rand io.Reader
+}
+
+// PrivateFilter is the output of PrivateBuilder
+type PrivateFilter struct {
+	// Filter is the built filter holding both real elements and decoys
+	Filter *Filter
+	// Decoys is the random decoy elements added into Filter
+	Decoys [][]byte
+	// FPRate is the estimated false positive rate of Filter
+	FPRate float64
+	// AnonymitySet is the expected number of elements of the universe
+	// matched by Filter, i.e. the real elements plus the false positives
+	AnonymitySet float64
+}
+
+// FilterLoad returns the filterload message to send to peers
+func (pf *PrivateFilter) FilterLoad() *wire.FilterLoad {
+	return pf.Filter.Snapshot()
+}
+
+// Build makes a filter holding the given real elements and random decoys
+func (b *PrivateBuilder) Build(elements [][]byte) (*PrivateFilter, error) {
+	nReal := uint64(len(elements))
+	if 0 == nReal || b.Universe <= nReal {
+		return nil, ErrInvalidPrivacy
+	}
+
+	// the targeted FP rate makes Level*nReal innocent elements match
+	P := float64(b.Level) * float64(nReal) / float64(b.Universe-nReal)
+	if P >= 1 {
+		// the universe is too small to hide the real elements
+		return nil, ErrInvalidPrivacy
+	}
+
+	r := randReader(b.Rand)
+
+	nDecoy, err := randDecoyCount(r, nReal)
+	if nil != err {
+		return nil, err
+	}
+	N := uint32(nReal + nDecoy)
+
+	filter, err := NewRandom(N, P, b.Flags, r)
+	if nil != err {
+		return nil, err
+	}
+
+	for _, v := range elements {
+		if err := filter.Add(v); nil != err {
+			return nil, err
+		}
+	}
+
+	decoys := make([][]byte, nDecoy)
+	for i := range decoys {
+		decoys[i] = make([]byte, DecoySize)
+		if _, err := io.ReadFull(r, decoys[i]); nil != err {
+			return nil, err
+		}
+
+		filter.Add(decoys[i])
+	}
+
+	snapshot := filter.Snapshot()
+	fpRate := EstimateFPRate(uint32(len(snapshot.Bits))<<3, snapshot.HashFuncs,
+		N)
+
+	return &PrivateFilter{
+		Filter:       filter,
+		Decoys:       decoys,
+		FPRate:       fpRate,
+		AnonymitySet: float64(nReal) + fpRate*float64(b.Universe-nReal),
+	}, nil
+}
+
+// randDecoyCount draws the number of decoys from [nReal, 3*nReal] uniformly
+func randDecoyCount(r io.Reader, nReal uint64) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); nil != err {
+		return 0, err
+	}
+
+	// the modulo bias is negligible for any practical nReal
+	return nReal + binary.LittleEndian.Uint64(buf[:])%(2*nReal+1), nil
+}
+
+// EstimateFPRate estimates the false positive rate of a filter of m bits and
+// k hash functions after n elements are added, i.e. (1-e^(-kn/m))^k
+func EstimateFPRate(m, k, n uint32) float64 {
+	if 0 == m {
+		return 1
+	}
+
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
@@ -0,0 +1,164 @@
+package bloom_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math"
+	"testing"
+
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// fakeElements generates n distinct 20-byte elements deterministically
+func fakeElements(prefix string, n int) [][]byte {
+	out := make([][]byte, n)
+	for i := range out {
+		h := sha256.Sum256([]byte(prefix + string(rune(i))))
+		out[i] = h[:20]
+	}
+
+	return out
+}
+
+func TestPrivateBuilder_Build(t *testing.T) {
+	const universe = 100000
+
+	elements := fakeElements("real", 16)
+
+	b := &bloom.PrivateBuilder{
+		Level:    bloom.PrivacyLow,
+		Universe: universe,
+		Flags:    wire.UpdateAll,
+		Rand:     bytes.NewReader(bytes.Repeat([]byte{0x5a}, 8+4+48*bloom.DecoySize)),
+	}
+
+	pf, err := b.Build(elements)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	for i, v := range elements {
+		if !pf.Filter.Match(v) {
+			t.Fatalf("#%d real element isn't matched", i)
+		}
+	}
+
+	if n := len(pf.Decoys); n < len(elements) || n > 3*len(elements) {
+		t.Fatalf("invalid #(decoy): got %d, expect within [%d, %d]", n,
+			len(elements), 3*len(elements))
+	}
+	for i, v := range pf.Decoys {
+		if !pf.Filter.Match(v) {
+			t.Fatalf("#%d decoy isn't matched", i)
+		}
+	}
+
+	if tweak := pf.FilterLoad().Tweak; tweak != 0x5a5a5a5a {
+		t.Fatalf("tweak isn't drawn from Rand: got %x", tweak)
+	}
+
+	// the anonymity set is expected around (1+Level)*#(real)
+	expect := float64(len(elements)) * (1 + float64(b.Level))
+	if math.Abs(pf.AnonymitySet-expect) > expect/2 {
+		t.Fatalf("invalid anonymity set: got %f, expect about %f",
+			pf.AnonymitySet, expect)
+	}
+
+	// check the estimation against the empirical FP rate over the universe
+	var nFP int
+	for _, v := range fakeElements("innocent", universe/10) {
+		if pf.Filter.Match(v) {
+			nFP++
+		}
+	}
+	if got := float64(nFP) / (universe / 10); math.Abs(got-pf.FPRate) > pf.FPRate {
+		t.Fatalf("invalid FP rate: got %f, estimated %f", got, pf.FPRate)
+	}
+}
+
+// TestPrivateBuilder_Build_padding checks the number of decoys varies, so
+// that the filter size doesn't tell the number of real elements
+func TestPrivateBuilder_Build_padding(t *testing.T) {
+	elements := fakeElements("real", 16)
+
+	sizes := make(map[int]bool)
+	nDecoys := make(map[int]bool)
+	for i := 0; i < 16; i++ {
+		b := &bloom.PrivateBuilder{
+			Level:    bloom.PrivacyLow,
+			Universe: 100000,
+			Flags:    wire.UpdateNone,
+		}
+
+		pf, err := b.Build(elements)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		sizes[len(pf.FilterLoad().Bits)] = true
+		nDecoys[len(pf.Decoys)] = true
+	}
+
+	if len(sizes) < 2 || len(nDecoys) < 2 {
+		t.Fatalf("padding should vary: %d sizes, %d decoy counts", len(sizes),
+			len(nDecoys))
+	}
+}
+
+func TestPrivateBuilder_Build_errors(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		b        *bloom.PrivateBuilder
+		elements [][]byte
+	}{
+		{
+			"no element",
+			&bloom.PrivateBuilder{Level: bloom.PrivacyLow, Universe: 1000},
+			nil,
+		},
+		{
+			"universe smaller than elements",
+			&bloom.PrivateBuilder{Level: bloom.PrivacyLow, Universe: 2},
+			fakeElements("real", 4),
+		},
+		{
+			"universe too small for the level",
+			&bloom.PrivateBuilder{Level: bloom.PrivacyHigh, Universe: 1000},
+			fakeElements("real", 4),
+		},
+		{
+			"exhausted randomness",
+			&bloom.PrivateBuilder{
+				Level:    bloom.PrivacyLow,
+				Universe: 1000,
+				Rand:     bytes.NewReader(make([]byte, 8)),
+			},
+			fakeElements("real", 4),
+		},
+	}
+
+	for i, c := range testCases {
+		if _, err := c.b.Build(c.elements); nil == err {
+			t.Fatalf("#%d [%s] should trigger error", i, c.desc)
+		}
+	}
+}
+
+func TestEstimateFPRate(t *testing.T) {
+	testCases := []struct {
+		m, k, n uint32
+		expect  float64
+	}{
+		{0, 3, 10, 1},
+		{80, 0, 10, 1},
+		{80, 3, 0, 0},
+		{1024, 7, 100, math.Pow(1-math.Exp(-700.0/1024), 7)},
+	}
+
+	for i, c := range testCases {
+		if got := bloom.EstimateFPRate(c.m, c.k, c.n); math.Abs(got-c.expect) > 1e-12 {
+			t.Fatalf("#%d invalid FP rate: got %f, expect %f", i, got, c.expect)
+		}
+	}
+}
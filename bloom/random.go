@@ -0,0 +1,44 @@
+package bloom
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/sammyne/bip37/wire"
+)
+
+// RandomTweak draws a tweak from r, which defaults to crypto/rand.Reader if
+// nil
+func RandomTweak(r io.Reader) (uint32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(randReader(r), buf[:]); nil != err {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+// NewRandom is the recommended constructor of a bloom filter, which works as
+// New but draws the tweak from r rather than falling back to the fixed Tweak,
+// so that filters of different clients can't be fingerprinted by their
+// hashing. r defaults to crypto/rand.Reader if nil, and is injectable for
+// deterministic tests. An optional C seed constant is accepted as by New.
+func NewRandom(N uint32, P float64, flags wire.BloomUpdateType, r io.Reader,
+	c ...uint32) (*Filter, error) {
+	tweak, err := RandomTweak(r)
+	if nil != err {
+		return nil, err
+	}
+
+	return New(N, P, flags, append([]uint32{tweak}, c...)...), nil
+}
+
+// randReader returns r if not nil, or crypto/rand.Reader otherwise
+func randReader(r io.Reader) io.Reader {
+	if nil == r {
+		return rand.Reader
+	}
+
+	return r
+}
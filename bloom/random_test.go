@@ -0,0 +1,67 @@
+package bloom_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestRandomTweak(t *testing.T) {
+	got, err := bloom.RandomTweak(bytes.NewReader(bip37.Unhexlify("01000080")))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if expect := uint32(2147483649); got != expect {
+		t.Fatalf("invalid tweak: got %d, expect %d", got, expect)
+	}
+}
+
+func TestRandomTweak_error(t *testing.T) {
+	if _, err := bloom.RandomTweak(bytes.NewReader([]byte{0x01})); nil == err {
+		t.Fatal("short reader should trigger error")
+	}
+}
+
+func TestNewRandom(t *testing.T) {
+	r := bytes.NewReader(bip37.Unhexlify("01000080"))
+
+	filter, err := bloom.NewRandom(3, 0.01, wire.UpdateAll, r)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// same as the case in TestNew_withTweak
+	expect := bloom.New(3, 0.01, wire.UpdateAll, 2147483649)
+
+	data := bip37.Unhexlify("99108ad8ed9bb6274d3980bab5a85c048f0950c8")
+	filter.Add(data)
+	expect.Add(data)
+
+	if got := filter.Snapshot(); !bytes.Equal(got.Bits, expect.Snapshot().Bits) ||
+		got.Tweak != expect.Snapshot().Tweak {
+		t.Fatalf("invalid snapshot: got %v, expect %v", got, expect.Snapshot())
+	}
+}
+
+func TestNewRandom_cryptoRand(t *testing.T) {
+	const nTrial = 4
+
+	tweaks := make(map[uint32]bool)
+	for i := 0; i < nTrial; i++ {
+		filter, err := bloom.NewRandom(3, 0.01, wire.UpdateAll, nil)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		tweaks[filter.Snapshot().Tweak] = true
+	}
+
+	// a collision among 4 random uint32 is practically impossible
+	if len(tweaks) != nTrial {
+		t.Fatalf("tweaks aren't random: %v", tweaks)
+	}
+}
@@ -0,0 +1,75 @@
+package bloom
+
+import "github.com/sammyne/bip37/wire"
+
+// Compatible checks if the other filter can be merged with f, i.e. both are
+// initialised and share the same size, HashFuncs, Tweak and C. It returns
+// ErrUninitialised or ErrIncompatible otherwise.
+func (f *Filter) Compatible(other *Filter) error {
+	snapshot, c := other.state()
+
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return f.compatible(snapshot, c)
+}
+
+// Intersect keeps only the bits set in both f and other, so that f matches
+// the elements matched by both filters
+func (f *Filter) Intersect(other *Filter) error {
+	return f.merge(other, func(x, y byte) byte { return x & y })
+}
+
+// Union sets all bits of other into f, so that f matches every element of
+// both filters. It's equivalent to adding all elements of other into f.
+func (f *Filter) Union(other *Filter) error {
+	return f.merge(other, func(x, y byte) byte { return x | y })
+}
+
+// CompatibleSnapshots checks if two snapshots share the same size, HashFuncs
+// and Tweak. A snapshot carries no C, so both are deemed to use the same one.
+func CompatibleSnapshots(a, b *wire.FilterLoad) bool {
+	return nil != a && nil != b && len(a.Bits) == len(b.Bits) &&
+		a.HashFuncs == b.HashFuncs && a.Tweak == b.Tweak
+}
+
+// compatible checks the given state against the one of f, which assumes the
+// caller holds the lock of f
+func (f *Filter) compatible(snapshot *wire.FilterLoad, c uint32) error {
+	if nil == f.snapshot || nil == snapshot {
+		return ErrUninitialised
+	}
+
+	if !CompatibleSnapshots(f.snapshot, snapshot) || f.c != c {
+		return ErrIncompatible
+	}
+
+	return nil
+}
+
+// merge combines the bits of other into f byte by byte with op
+func (f *Filter) merge(other *Filter, op func(x, y byte) byte) error {
+	// take a copy first, so that merging a filter with itself won't deadlock
+	snapshot, c := other.state()
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if err := f.compatible(snapshot, c); nil != err {
+		return err
+	}
+
+	for i, v := range snapshot.Bits {
+		f.snapshot.Bits[i] = op(f.snapshot.Bits[i], v)
+	}
+
+	return nil
+}
+
+// state returns a copy of the snapshot together with C
+func (f *Filter) state() (*wire.FilterLoad, uint32) {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return copySnapshot(f.snapshot), f.c
+}
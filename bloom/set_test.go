@@ -0,0 +1,115 @@
+package bloom_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestFilter_Union(t *testing.T) {
+	accounts := [][][]byte{
+		fakeElements("account0", 8),
+		fakeElements("account1", 8),
+	}
+
+	expect := bloom.New(16, 0.0001, wire.UpdateAll, bloom.Tweak)
+	filters := make([]*bloom.Filter, len(accounts))
+	for i, elements := range accounts {
+		filters[i] = bloom.New(16, 0.0001, wire.UpdateAll, bloom.Tweak)
+		for _, v := range elements {
+			filters[i].Add(v)
+			expect.Add(v)
+		}
+	}
+
+	if err := filters[0].Union(filters[1]); nil != err {
+		t.Fatal(err)
+	}
+
+	if got := filters[0].Snapshot(); !reflect.DeepEqual(got, expect.Snapshot()) {
+		t.Fatalf("invalid union: got %v, expect %v", got, expect.Snapshot())
+	}
+
+	// union with itself is a no-op
+	if err := filters[0].Union(filters[0]); nil != err {
+		t.Fatal(err)
+	}
+	if got := filters[0].Snapshot(); !reflect.DeepEqual(got, expect.Snapshot()) {
+		t.Fatalf("invalid self-union: got %v, expect %v", got, expect.Snapshot())
+	}
+}
+
+func TestFilter_Intersect(t *testing.T) {
+	common := fakeElements("common", 4)
+
+	a := bloom.New(16, 0.0001, wire.UpdateAll, bloom.Tweak)
+	b := bloom.New(16, 0.0001, wire.UpdateAll, bloom.Tweak)
+	for _, v := range common {
+		a.Add(v)
+		b.Add(v)
+	}
+
+	onlyA := fakeElements("a", 4)
+	for _, v := range onlyA {
+		a.Add(v)
+	}
+
+	if err := a.Intersect(b); nil != err {
+		t.Fatal(err)
+	}
+
+	for i, v := range common {
+		if !a.Match(v) {
+			t.Fatalf("#%d common element is lost", i)
+		}
+	}
+
+	// the intersection is bounded by b
+	if got := a.Snapshot(); !reflect.DeepEqual(got, b.Snapshot()) {
+		t.Fatalf("invalid intersection: got %v, expect %v", got, b.Snapshot())
+	}
+}
+
+func TestFilter_Compatible(t *testing.T) {
+	cleared := bloom.New(16, 0.0001, wire.UpdateAll)
+	cleared.Clear()
+
+	base := bloom.New(16, 0.0001, wire.UpdateAll, bloom.Tweak, bloom.C)
+
+	testCases := []struct {
+		desc   string
+		other  *bloom.Filter
+		expect error
+	}{
+		{"same", bloom.New(16, 0.0001, wire.UpdateNone, bloom.Tweak, bloom.C), nil},
+		{"size", bloom.New(32, 0.0001, wire.UpdateAll, bloom.Tweak, bloom.C), bloom.ErrIncompatible},
+		{"hash funcs", bloom.New(16, 0.01, wire.UpdateAll, bloom.Tweak, bloom.C), bloom.ErrIncompatible},
+		{"tweak", bloom.New(16, 0.0001, wire.UpdateAll, bloom.Tweak+1, bloom.C), bloom.ErrIncompatible},
+		{"C", bloom.New(16, 0.0001, wire.UpdateAll, bloom.Tweak, bloom.C+1), bloom.ErrIncompatible},
+		{"cleared", cleared, bloom.ErrUninitialised},
+	}
+
+	for i, c := range testCases {
+		if err := base.Compatible(c.other); err != c.expect {
+			t.Fatalf("#%d [%s] invalid error: got %v, expect %v", i, c.desc, err,
+				c.expect)
+		}
+
+		if c.expect == nil {
+			continue
+		}
+
+		before := base.Snapshot()
+		if err := base.Union(c.other); err != c.expect {
+			t.Fatalf("#%d [%s] union should fail: got %v", i, c.desc, err)
+		}
+		if err := base.Intersect(c.other); err != c.expect {
+			t.Fatalf("#%d [%s] intersection should fail: got %v", i, c.desc, err)
+		}
+		if !reflect.DeepEqual(base.Snapshot(), before) {
+			t.Fatalf("#%d [%s] failed merge shouldn't touch the filter", i, c.desc)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package bloom
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// TaprootKeySize is the length of an x-only taproot output key
+const TaprootKeySize = 32
+
+// AddTaprootKey takes the output key of a P2TR output into record, which is
+// the very element pushed by the output script. The key is either x-only of
+// 32 bytes or compressed of 33 bytes, whose prefix is dropped. Keypath spends
+// push no element, so they're only matched by the OutPoint, which requires
+// UpdateAll or AddOutPoint.
+func (f *Filter) AddTaprootKey(key []byte) error {
+	xonly, err := xonlyKey(key)
+	if nil != err {
+		return err
+	}
+
+	return f.Add(xonly)
+}
+
+// TaprootKey extracts the x-only output key from a P2TR script, i.e.
+// `OP_1 <32-byte key>`, and returns false if the script isn't P2TR
+func TaprootKey(pkScript []byte) ([]byte, bool) {
+	if len(pkScript) != 2+TaprootKeySize || txscript.OP_1 != pkScript[0] ||
+		txscript.OP_DATA_32 != pkScript[1] {
+		return nil, false
+	}
+
+	return pkScript[2:], true
+}
+
+// TaprootOutputKey tweaks the internal key into the x-only output key as
+// BIP341 specifies, i.e. Q = P + H_TapTweak(P||merkleRoot)*G, where the
+// merkleRoot is nil for outputs without script path.
+func TaprootOutputKey(internalKey, merkleRoot []byte) ([]byte, error) {
+	xonly, err := xonlyKey(internalKey)
+	if nil != err {
+		return nil, err
+	}
+
+	// lift_x picks the point of even y
+	P, err := btcec.ParsePubKey(append([]byte{0x02}, xonly...), btcec.S256())
+	if nil != err {
+		return nil, ErrInvalidTaprootKey
+	}
+
+	t := taggedHash("TapTweak", xonly, merkleRoot)
+	if new(big.Int).SetBytes(t).Cmp(btcec.S256().N) >= 0 {
+		return nil, ErrInvalidTaprootKey
+	}
+
+	curve := btcec.S256()
+	tx, ty := curve.ScalarBaseMult(t)
+	qx, _ := curve.Add(P.X, P.Y, tx, ty)
+
+	out := make([]byte, TaprootKeySize)
+	qx.FillBytes(out)
+
+	return out, nil
+}
+
+// taggedHash implements the tagged hash of BIP340, i.e.
+// SHA256(SHA256(tag)||SHA256(tag)||data...)
+func taggedHash(tag string, data ...[]byte) []byte {
+	h := sha256.Sum256([]byte(tag))
+
+	hasher := sha256.New()
+	hasher.Write(h[:])
+	hasher.Write(h[:])
+	for _, v := range data {
+		hasher.Write(v)
+	}
+
+	return hasher.Sum(nil)
+}
+
+// xonlyKey normalizes a x-only or compressed key into the x-only form
+func xonlyKey(key []byte) ([]byte, error) {
+	switch {
+	case TaprootKeySize == len(key):
+		return key, nil
+	case TaprootKeySize+1 == len(key) && (0x02 == key[0] || 0x03 == key[0]):
+		return key[1:], nil
+	}
+
+	return nil, ErrInvalidTaprootKey
+}
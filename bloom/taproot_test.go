@@ -0,0 +1,101 @@
+package bloom_test
+
+import (
+	"bytes"
+	"testing"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// the first receiving key of the test vector of BIP86, i.e. m/86'/0'/0'/0/0
+var (
+	bip86InternalKey = bip37.Unhexlify("cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115")
+	bip86OutputKey   = bip37.Unhexlify("a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c")
+)
+
+// p2trTx makes a tx paying to the given x-only output key
+func p2trTx(key []byte) *btcutil.Tx {
+	msg := btcwire.NewMsgTx(btcwire.TxVersion)
+	msg.AddTxIn(btcwire.NewTxIn(bip37.NewOutPoint(make([]byte, 32), 0), nil, nil))
+	msg.AddTxOut(btcwire.NewTxOut(1000, append([]byte{0x51, 0x20}, key...)))
+
+	return btcutil.NewTx(msg)
+}
+
+func TestTaprootOutputKey(t *testing.T) {
+	got, err := bloom.TaprootOutputKey(bip86InternalKey, nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, bip86OutputKey) {
+		t.Fatalf("invalid output key: got %x, expect %x", got, bip86OutputKey)
+	}
+
+	if _, err := bloom.TaprootOutputKey(make([]byte, 31), nil); bloom.ErrInvalidTaprootKey != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, bloom.ErrInvalidTaprootKey)
+	}
+}
+
+func TestTaprootKey(t *testing.T) {
+	testCases := []struct {
+		pkScript []byte
+		expect   []byte
+	}{
+		{append([]byte{0x51, 0x20}, bip86OutputKey...), bip86OutputKey},
+		{append([]byte{0x00, 0x20}, bip86OutputKey...), nil},
+		{append([]byte{0x51, 0x20}, bip86OutputKey[1:]...), nil},
+	}
+
+	for i, c := range testCases {
+		got, ok := bloom.TaprootKey(c.pkScript)
+		if ok != (nil != c.expect) || !bytes.Equal(got, c.expect) {
+			t.Fatalf("#%d invalid key: got %x, expect %x", i, got, c.expect)
+		}
+	}
+}
+
+func TestFilter_AddTaprootKey(t *testing.T) {
+	tx := p2trTx(bip86OutputKey)
+
+	elems := bloom.ExtractElements(tx)
+	if !elems.Outputs[0].Taproot {
+		t.Fatal("P2TR output isn't recognized")
+	}
+
+	testCases := []struct {
+		key    []byte
+		flags  wire.BloomUpdateType
+		expect int // #(tracked OutPoint)
+	}{
+		{bip86OutputKey, wire.UpdateAll, 1},
+		{append([]byte{0x03}, bip86OutputKey...), wire.UpdateP2PubKeyOnly, 0},
+		// P2TR isn't covered by the policy
+		{bip86OutputKey, wire.UpdateP2PubKeyOrScriptHash, 0},
+	}
+
+	for i, c := range testCases {
+		filter := bloom.New(10, 0.000001, c.flags, bloom.Tweak)
+		if err := filter.AddTaprootKey(c.key); nil != err {
+			t.Fatalf("#%d unexpected error: %v", i, err)
+		}
+
+		ok, tracked := filter.MatchTxAndTrack(tx)
+		if !ok {
+			t.Fatalf("#%d matching is expected", i)
+		}
+
+		if len(tracked) != c.expect {
+			t.Fatalf("#%d invalid #(tracked): got %d, expect %d", i, len(tracked),
+				c.expect)
+		}
+	}
+
+	if err := bloom.New(10, 0.01, wire.UpdateAll).AddTaprootKey([]byte{0x01}); bloom.ErrInvalidTaprootKey != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, bloom.ErrInvalidTaprootKey)
+	}
+}
@@ -0,0 +1,92 @@
+// Package client implements the client side of BIP37, i.e. the support a
+// light client needs to consume filtered data from full nodes.
+package client
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/merkle"
+)
+
+// FilteredBlock is a merkle block paired with the matched txs sent after it
+type FilteredBlock struct {
+	Header btcwire.BlockHeader
+	// Matched is the txids proved by the merkle block in block order
+	Matched []*chainhash.Hash
+	// Txs is the delivered matched txs in the order of Matched
+	Txs []*btcutil.Tx
+	// Missing is the matched txids not delivered, which the server skips if
+	// the client is known to have them already
+	Missing []*chainhash.Hash
+}
+
+// Collector pairs merkle blocks with the tx messages trailing them. A group
+// starts by a merkle block, and ends by the next merkle block, a ping, or
+// Flush. Servers send a ping after the filtered blocks requested by a getdata
+// so that the client learns when the last group ends.
+// Collector isn't safe for concurrent use.
+type Collector struct {
+	pending *FilteredBlock
+	// txs maps the txids matched by the pending group to the txs delivered
+	// so far, where nil values are placeholders of undelivered ones
+	txs map[chainhash.Hash]*btcutil.Tx
+}
+
+// Flush ends the pending group if any, and returns it
+func (c *Collector) Flush() *FilteredBlock {
+	block := c.pending
+	if nil == block {
+		return nil
+	}
+
+	for _, h := range block.Matched {
+		if tx := c.txs[*h]; nil != tx {
+			block.Txs = append(block.Txs, tx)
+		} else {
+			block.Missing = append(block.Missing, h)
+		}
+	}
+
+	c.pending, c.txs = nil, nil
+
+	return block
+}
+
+// Handle takes a message from the server, and returns the group it ends if
+// any. Merkle blocks failing to verify are rejected with
+// ErrInvalidMerkleBlock without ending the pending group. Txs not matched by
+// the pending group, e.g. ones relayed from mempool, and other messages are
+// ignored.
+func (c *Collector) Handle(msg btcwire.Message) (*FilteredBlock, error) {
+	switch msg := msg.(type) {
+	case *btcwire.MsgMerkleBlock:
+		matched, ok := merkle.Parse(msg)
+		if !ok {
+			return nil, ErrInvalidMerkleBlock
+		}
+
+		block := c.Flush()
+
+		c.pending = &FilteredBlock{Header: msg.Header, Matched: matched}
+		c.txs = make(map[chainhash.Hash]*btcutil.Tx, len(matched))
+		for _, h := range matched {
+			c.txs[*h] = nil
+		}
+
+		return block, nil
+	case *btcwire.MsgTx:
+		if nil == c.pending {
+			return nil, nil
+		}
+
+		tx := btcutil.NewTx(msg)
+		if v, ok := c.txs[*tx.Hash()]; ok && nil == v {
+			c.txs[*tx.Hash()] = tx
+		}
+	case *btcwire.MsgPing:
+		return c.Flush(), nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,112 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/client"
+	"github.com/sammyne/bip37/merkle"
+	"github.com/sammyne/bip37/wire"
+)
+
+// filteredBlock builds the merkle block of the test block matching the txs
+// indexed by idx, and returns it with the matched txs
+func filteredBlock(t *testing.T, idx ...int) (*btcwire.MsgMerkleBlock,
+	[]*btcwire.MsgTx) {
+	block := bip37.ReadBlock(t)
+
+	filter := bloom.New(10, 0.000001, wire.UpdateNone, bloom.Tweak)
+	for _, i := range idx {
+		h := block.Transactions[i].TxHash()
+		filter.Add(h[:])
+	}
+
+	merkleBlock, hits := merkle.New(block, filter)
+
+	txs := make([]*btcwire.MsgTx, len(hits))
+	for i, j := range hits {
+		txs[i] = block.Transactions[j]
+	}
+
+	return merkleBlock, txs
+}
+
+func TestCollector(t *testing.T) {
+	block1, txs1 := filteredBlock(t, 1, 3)
+	block2, txs2 := filteredBlock(t, 2)
+	if len(txs1) < 2 || len(txs2) < 1 {
+		t.Fatalf("too few hits to test: %d, %d", len(txs1), len(txs2))
+	}
+
+	unrelated := btcwire.NewMsgTx(btcwire.TxVersion)
+	unrelated.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{}, 0), nil, nil))
+
+	// the first matched tx of block1 is skipped by the server as known
+	msgs := []btcwire.Message{
+		block1, txs1[1], unrelated,
+		block2, txs2[0], txs2[0],
+		btcwire.NewMsgPing(1),
+	}
+
+	var (
+		c   client.Collector
+		got []*client.FilteredBlock
+	)
+	for i, msg := range msgs {
+		block, err := c.Handle(msg)
+		if nil != err {
+			t.Fatalf("#%d unexpected error: %v", i, err)
+		}
+		if nil != block {
+			got = append(got, block)
+		}
+	}
+
+	if nil != c.Flush() {
+		t.Fatal("ping should have ended the last group")
+	}
+
+	if 2 != len(got) {
+		t.Fatalf("invalid #(block): got %d, expect 2", len(got))
+	}
+
+	if got[0].Header.BlockHash() != block1.Header.BlockHash() {
+		t.Fatal("invalid header of the 1st block")
+	}
+	if 1 != len(got[0].Txs) || *got[0].Txs[0].Hash() != txs1[1].TxHash() {
+		t.Fatalf("invalid txs of the 1st block: %v", got[0].Txs)
+	}
+	if 1 != len(got[0].Missing) || *got[0].Missing[0] != txs1[0].TxHash() {
+		t.Fatalf("invalid missing txids of the 1st block: %v", got[0].Missing)
+	}
+
+	if 1 != len(got[1].Txs) || 0 != len(got[1].Missing) {
+		t.Fatalf("invalid 2nd block: %d txs, %d missing", len(got[1].Txs),
+			len(got[1].Missing))
+	}
+}
+
+func TestCollector_Handle_invalid(t *testing.T) {
+	block, txs := filteredBlock(t, 1)
+
+	var c client.Collector
+	if _, err := c.Handle(block); nil != err {
+		t.Fatal(err)
+	}
+
+	tampered := *block
+	tampered.Header.MerkleRoot = chainhash.Hash{}
+	if _, err := c.Handle(&tampered); client.ErrInvalidMerkleBlock != err {
+		t.Fatalf("invalid error: got %v, expect %v", err,
+			client.ErrInvalidMerkleBlock)
+	}
+
+	// the pending group survives the invalid merkle block
+	c.Handle(txs[0])
+	if got := c.Flush(); nil == got || len(got.Txs) != len(txs) {
+		t.Fatalf("invalid pending group: %+v", got)
+	}
+}
@@ -0,0 +1,6 @@
+package client
+
+import "errors"
+
+// ErrInvalidMerkleBlock signals the merkle block fails to verify
+var ErrInvalidMerkleBlock = errors.New("invalid merkle block")
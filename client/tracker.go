@@ -0,0 +1,213 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37/headers"
+	"github.com/sammyne/bip37/merkle"
+)
+
+// HeaderSource tells the confirmations of blocks, which is implemented by
+// headers.Chain
+type HeaderSource interface {
+	// Confirmations returns 0 if the block isn't on the best chain
+	Confirmations(hash *chainhash.Hash) uint32
+}
+
+// Inclusion records a tx proved to be included in a block
+type Inclusion struct {
+	TxID      chainhash.Hash
+	BlockHash chainhash.Hash
+	// Index is the position of the tx in the block
+	Index uint32
+}
+
+// EventType enumerates the events published by Tracker
+type EventType uint8
+
+// Enumerations of event types
+const (
+	// Confirmed signals the tx is included in the best chain
+	Confirmed EventType = iota
+	// Unconfirmed signals the including block of the tx leaves the best chain
+	Unconfirmed
+)
+
+// Event notifies a change of the confirmation status of a tracked tx
+type Event struct {
+	Type EventType
+	// Inclusion is the newly confirming one for Confirmed, and the one
+	// leaving the best chain for Unconfirmed
+	Inclusion Inclusion
+}
+
+// Tracker tracks the confirmations of matched txs by the inclusions proved by
+// merkle blocks, and publishes events as the best chain changes. A tx may be
+// included by blocks on different branches, where at most one of them is on
+// the best chain. It is safe for concurrent use.
+type Tracker struct {
+	mtx sync.Mutex
+
+	source HeaderSource
+	// inclusions collects the inclusions of each tx from all branches
+	inclusions map[chainhash.Hash][]Inclusion
+	// order is the tracked txids in the order of first inclusion, which keeps
+	// the events deterministic
+	order []chainhash.Hash
+	// confirmed is the inclusions on the best chain keyed by txid
+	confirmed map[chainhash.Hash]Inclusion
+
+	handlers map[uint64]func(Event)
+	nextID   uint64
+}
+
+// AddMerkleBlock records the inclusions proved by the merkle block, and
+// publishes events of txs confirmed consequently. The merkle block isn't
+// required to be on the best chain.
+func (t *Tracker) AddMerkleBlock(msg *btcwire.MsgMerkleBlock) error {
+	matched, indices, ok := merkle.ParseWithIndices(msg)
+	if !ok {
+		return ErrInvalidMerkleBlock
+	}
+
+	blockHash := msg.Header.BlockHash()
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for i, h := range matched {
+		if t.included(h, &blockHash) {
+			continue
+		}
+
+		if _, ok := t.inclusions[*h]; !ok {
+			t.order = append(t.order, *h)
+		}
+		t.inclusions[*h] = append(t.inclusions[*h], Inclusion{
+			TxID:      *h,
+			BlockHash: blockHash,
+			Index:     indices[i],
+		})
+	}
+
+	t.refresh()
+
+	return nil
+}
+
+// Confirmations returns the number of confirmations of the tx and the
+// including block on the best chain. 0 is returned if the tx isn't confirmed.
+func (t *Tracker) Confirmations(txid *chainhash.Hash) (uint32, Inclusion) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	inclusion, ok := t.confirmed[*txid]
+	if !ok {
+		return 0, Inclusion{}
+	}
+
+	return t.source.Confirmations(&inclusion.BlockHash), inclusion
+}
+
+// HandleTipUpdate re-evaluates the tracked txs upon the change of the best
+// chain, e.g. as returned by headers.Chain.Connect, and publishes events
+// accordingly. A nil update is a no-op.
+func (t *Tracker) HandleTipUpdate(update *headers.TipUpdate) {
+	if nil == update {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.refresh()
+}
+
+// Subscribe registers the handler to be called with every event in order, and
+// returns the function to cancel the subscription. Handlers are called
+// synchronously while the tracker is locked, so they mustn't call back into
+// the tracker.
+func (t *Tracker) Subscribe(handler func(Event)) (cancel func()) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	id := t.nextID
+	t.nextID++
+	t.handlers[id] = handler
+
+	return func() {
+		t.mtx.Lock()
+		defer t.mtx.Unlock()
+
+		delete(t.handlers, id)
+	}
+}
+
+func (t *Tracker) included(txid, blockHash *chainhash.Hash) bool {
+	for _, v := range t.inclusions[*txid] {
+		if v.BlockHash == *blockHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Tracker) publish(event Event) {
+	for id := uint64(0); id < t.nextID; id++ {
+		if handler, ok := t.handlers[id]; ok {
+			handler(event)
+		}
+	}
+}
+
+// refresh checks every tracked tx against the best chain, where unconfirming
+// events are published before confirming ones so that a tx moving between
+// branches is reported in order. Txs of either kind are visited in the order
+// they are tracked.
+func (t *Tracker) refresh() {
+	var confirming []Inclusion
+	for _, txid := range t.order {
+		inclusions := t.inclusions[txid]
+		var (
+			best  Inclusion
+			found bool
+		)
+		for _, v := range inclusions {
+			if 0 != t.source.Confirmations(&v.BlockHash) {
+				best, found = v, true
+				break
+			}
+		}
+
+		old, ok := t.confirmed[txid]
+		switch {
+		case ok && found && old.BlockHash == best.BlockHash:
+			continue
+		case ok:
+			delete(t.confirmed, txid)
+			t.publish(Event{Type: Unconfirmed, Inclusion: old})
+		}
+
+		if found {
+			t.confirmed[txid] = best
+			confirming = append(confirming, best)
+		}
+	}
+
+	for _, v := range confirming {
+		t.publish(Event{Type: Confirmed, Inclusion: v})
+	}
+}
+
+// NewTracker makes a tracker following the best chain of source
+func NewTracker(source HeaderSource) *Tracker {
+	return &Tracker{
+		source:     source,
+		inclusions: make(map[chainhash.Hash][]Inclusion),
+		confirmed:  make(map[chainhash.Hash]Inclusion),
+		handlers:   make(map[uint64]func(Event)),
+	}
+}
@@ -0,0 +1,225 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/client"
+	"github.com/sammyne/bip37/headers"
+	"github.com/sammyne/bip37/merkle"
+	"github.com/sammyne/bip37/wire"
+)
+
+var params = &chaincfg.RegressionNetParams
+
+// newTx makes a tx distinguished by label
+func newTx(label string) *btcwire.MsgTx {
+	tx := btcwire.NewMsgTx(btcwire.TxVersion)
+	tx.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{}, 0xffffffff),
+		[]byte(label), nil))
+	tx.AddTxOut(btcwire.NewTxOut(50, []byte{0x51}))
+
+	return tx
+}
+
+// mineBlock makes a block extending parent with a coinbase labelled by label
+// and txs, which passes the proof of work of regtest
+func mineBlock(parent *btcwire.BlockHeader, label string,
+	txs ...*btcwire.MsgTx) *btcwire.MsgBlock {
+	all := append([]*btcwire.MsgTx{newTx(label)}, txs...)
+
+	utxs := make([]*btcutil.Tx, len(all))
+	for i, tx := range all {
+		utxs[i] = btcutil.NewTx(tx)
+	}
+	merkles := blockchain.BuildMerkleTreeStore(utxs, false)
+
+	block := btcwire.NewMsgBlock(&btcwire.BlockHeader{
+		Version:    1,
+		PrevBlock:  parent.BlockHash(),
+		MerkleRoot: *merkles[len(merkles)-1],
+		Timestamp:  parent.Timestamp.Add(10 * time.Minute),
+		Bits:       params.PowLimitBits,
+	})
+	for _, tx := range all {
+		block.AddTransaction(tx)
+	}
+
+	target := blockchain.CompactToBig(block.Header.Bits)
+	for {
+		hash := block.Header.BlockHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			return block
+		}
+		block.Header.Nonce++
+	}
+}
+
+// newMerkleBlock makes the merkle block of block matching the txs
+func newMerkleBlock(block *btcwire.MsgBlock,
+	txs ...*btcwire.MsgTx) *btcwire.MsgMerkleBlock {
+	filter := bloom.New(10, 0.000001, wire.UpdateNone, bloom.Tweak)
+	for _, tx := range txs {
+		h := tx.TxHash()
+		filter.Add(h[:])
+	}
+
+	msg, _ := merkle.New(block, filter)
+	return msg
+}
+
+func TestTracker(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	tracker := client.NewTracker(chain)
+
+	var events []client.Event
+	cancel := tracker.Subscribe(func(e client.Event) {
+		events = append(events, e)
+	})
+
+	connect := func(block *btcwire.MsgBlock) {
+		update, err := chain.Connect(&block.Header)
+		if nil != err {
+			t.Fatal(err)
+		}
+		tracker.HandleTipUpdate(update)
+	}
+
+	T, U := newTx("T"), newTx("U")
+	txidT, txidU := T.TxHash(), U.TxHash()
+
+	a1 := mineBlock(&params.GenesisBlock.Header, "a1", T)
+	a2 := mineBlock(&a1.Header, "a2", U)
+	b1 := mineBlock(&params.GenesisBlock.Header, "b1", T)
+	b2 := mineBlock(&b1.Header, "b2")
+	b3 := mineBlock(&b2.Header, "b3")
+
+	connect(a1)
+	if err := tracker.AddMerkleBlock(newMerkleBlock(a1, T)); nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(events) || client.Confirmed != events[0].Type ||
+		events[0].Inclusion.TxID != txidT || 1 != events[0].Inclusion.Index {
+		t.Fatalf("invalid events: %+v", events)
+	}
+
+	// blocks off the best chain confirm nothing
+	if err := tracker.AddMerkleBlock(newMerkleBlock(b1, T)); nil != err {
+		t.Fatal(err)
+	}
+	connect(b1)
+	if 1 != len(events) {
+		t.Fatalf("unexpected events: %+v", events[1:])
+	}
+
+	connect(a2)
+	if err := tracker.AddMerkleBlock(newMerkleBlock(a2, U)); nil != err {
+		t.Fatal(err)
+	}
+	if n, inclusion := tracker.Confirmations(&txidT); 2 != n ||
+		inclusion.BlockHash != a1.BlockHash() {
+		t.Fatalf("invalid confirmations of T: %d in %v", n, inclusion.BlockHash)
+	}
+
+	events = events[:0]
+	connect(b2)
+	connect(b3)
+
+	// T and U are unconfirmed in the order of tracking, and then T is
+	// confirmed by b1
+	expect := []client.Event{
+		{client.Unconfirmed, client.Inclusion{txidT, a1.BlockHash(), 1}},
+		{client.Unconfirmed, client.Inclusion{txidU, a2.BlockHash(), 1}},
+		{client.Confirmed, client.Inclusion{txidT, b1.BlockHash(), 1}},
+	}
+	if !reflect.DeepEqual(events, expect) {
+		t.Fatalf("invalid events: got %+v, expect %+v", events, expect)
+	}
+
+	if n, _ := tracker.Confirmations(&txidT); 3 != n {
+		t.Fatalf("invalid confirmations of T: got %d, expect 3", n)
+	}
+	if n, _ := tracker.Confirmations(&txidU); 0 != n {
+		t.Fatalf("invalid confirmations of U: got %d, expect 0", n)
+	}
+
+	cancel()
+	events = events[:0]
+	connect(mineBlock(&a2.Header, "a3"))
+	connect(mineBlock(&b3.Header, "b4"))
+	if 0 != len(events) {
+		t.Fatalf("unexpected events after cancelling: %+v", events)
+	}
+}
+
+func TestTracker_AddMerkleBlock_invalid(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	T := newTx("T")
+	msg := newMerkleBlock(mineBlock(&params.GenesisBlock.Header, "a1", T), T)
+	msg.Header.MerkleRoot = chainhash.Hash{}
+
+	err = client.NewTracker(chain).AddMerkleBlock(msg)
+	if client.ErrInvalidMerkleBlock != err {
+		t.Fatalf("invalid error: got %v, expect %v", err,
+			client.ErrInvalidMerkleBlock)
+	}
+}
+
+// a 7-tx block matching txs 4 and 6 takes exactly 8 flag bits, which fill up
+// a whole byte
+func TestTracker_AddMerkleBlock_wholeFlagBytes(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var txs []*btcwire.MsgTx
+	for _, label := range []string{"1", "2", "3", "4", "5", "6"} {
+		txs = append(txs, newTx(label))
+	}
+	block := mineBlock(&params.GenesisBlock.Header, "a1", txs...)
+
+	if _, err := chain.Connect(&block.Header); nil != err {
+		t.Fatal(err)
+	}
+
+	tracker := client.NewTracker(chain)
+
+	var events []client.Event
+	tracker.Subscribe(func(e client.Event) {
+		events = append(events, e)
+	})
+
+	msg := newMerkleBlock(block, txs[3], txs[5])
+	if 1 != len(msg.Flags) {
+		t.Fatalf("the fixture should take a whole flag byte: %x", msg.Flags)
+	}
+
+	if err := tracker.AddMerkleBlock(msg); nil != err {
+		t.Fatal(err)
+	}
+
+	hash := block.BlockHash()
+	expect := []client.Event{
+		{client.Confirmed, client.Inclusion{txs[3].TxHash(), hash, 4}},
+		{client.Confirmed, client.Inclusion{txs[5].TxHash(), hash, 6}},
+	}
+	if !reflect.DeepEqual(events, expect) {
+		t.Fatalf("invalid events: got %+v, expect %+v", events, expect)
+	}
+}
@@ -0,0 +1,139 @@
+// Command leakage plays a curious full node against captured filters of the
+// same client and reports the candidate elements probably owned by the client.
+//
+// Usage:
+//  leakage -filter a.json -filter b.json [-elements candidates.txt] [-block block.json]
+//
+// Each filter file is a bloom.Filter encoded by json.Marshal, i.e.
+//  {"bits": "<hex>", "hashFuncs": 11, "tweak": 0, "flags": 0, "c": 4221880213}
+// where "c" is the C seed constant and optional. Since filterload messages
+// carry no C, filters captured from the wire can omit it and it defaults to
+// bloom.C. The elements file lists one hex-encoded candidate element per
+// line, and each block file is a JSON-encoded btcd wire.MsgBlock as the
+// testdata.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/leakage"
+)
+
+// files collects the values of a repeatable flag
+type files []string
+
+func (f *files) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *files) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	var filterFiles, blockFiles files
+	flag.Var(&filterFiles, "filter", "JSON file of a captured filter (repeatable)")
+	flag.Var(&blockFiles, "block", "JSON file of a block to draw candidates from (repeatable)")
+	elementsFile := flag.String("elements", "", "file of hex-encoded candidate elements, one per line")
+	all := flag.Bool("all", false, "report candidates matched by any filter rather than all")
+	flag.Parse()
+
+	if 0 == len(filterFiles) || ("" == *elementsFile && 0 == len(blockFiles)) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	filters := make([]*bloom.Filter, len(filterFiles))
+	for i, v := range filterFiles {
+		filters[i] = new(bloom.Filter)
+		if err := readJSON(v, filters[i]); nil != err {
+			fatalf("failed to read filter %s: %v", v, err)
+		}
+	}
+
+	var candidates [][]byte
+	if "" != *elementsFile {
+		elements, err := readElements(*elementsFile)
+		if nil != err {
+			fatalf("failed to read elements: %v", err)
+		}
+		candidates = elements
+	}
+
+	blocks := make([]*btcwire.MsgBlock, len(blockFiles))
+	for i, v := range blockFiles {
+		blocks[i] = new(btcwire.MsgBlock)
+		if err := readJSON(v, blocks[i]); nil != err {
+			fatalf("failed to read block %s: %v", v, err)
+		}
+	}
+	candidates = append(candidates, leakage.BlockElements(blocks...)...)
+
+	report := leakage.New(filters...).Elements(candidates)
+
+	fmt.Printf("filters: %d\n", report.Filters)
+	for i, v := range report.FPRates {
+		fmt.Printf("  #%d estimated FP rate: %.6g\n", i, v)
+	}
+	fmt.Printf("tested candidates: %d\n", report.Tested)
+	fmt.Printf("expected innocents matching all filters: %.3f\n",
+		report.ExpectedFalsePositives())
+
+	for _, c := range report.Candidates {
+		if !*all && !c.Probable(report.Filters) {
+			continue
+		}
+
+		fmt.Printf("%x\thits=%d/%d\tfp=%.6g\n", c.Element, c.Hits,
+			report.Filters, c.FPProbability)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+func readElements(path string) ([][]byte, error) {
+	fd, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var out [][]byte
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if "" == line || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		v, err := hex.DecodeString(line)
+		if nil != err {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, scanner.Err()
+}
+
+func readJSON(path string, v interface{}) error {
+	fd, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer fd.Close()
+
+	return json.NewDecoder(fd).Decode(v)
+}
@@ -0,0 +1,47 @@
+package gcs
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// BuildBasic makes the basic filter of the block, whose items are
+//  - the public key script of each output, except empty and OP_RETURN ones
+//  - the public key script of each output spent by the block, except empty
+//    ones, which are resolved by the caller as prevScripts
+func BuildBasic(block *wire.MsgBlock, prevScripts [][]byte) *Filter {
+	var items [][]byte
+	for _, tx := range block.Transactions {
+		for _, out := range tx.TxOut {
+			if 0 == len(out.PkScript) || txscript.OP_RETURN == out.PkScript[0] {
+				continue
+			}
+
+			items = append(items, out.PkScript)
+		}
+	}
+
+	for _, v := range prevScripts {
+		if 0 != len(v) {
+			items = append(items, v)
+		}
+	}
+
+	hash := block.BlockHash()
+	return Build(Key(&hash), items, P, M)
+}
+
+// BasicFromBytes deserializes a basic filter
+func BasicFromBytes(data []byte) (*Filter, error) {
+	return FromBytes(data, P, M)
+}
+
+// Key derives the SipHash key of a block's filters, which is the first
+// KeySize bytes of the block hash in the internal byte order
+func Key(blockHash *chainhash.Hash) [KeySize]byte {
+	var key [KeySize]byte
+	copy(key[:], blockHash[:])
+
+	return key
+}
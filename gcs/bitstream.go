@@ -0,0 +1,66 @@
+package gcs
+
+import "io"
+
+// bitWriter writes bits in the most-significant-first order
+type bitWriter struct {
+	data []byte
+	// n is the number of bits written
+	n uint
+}
+
+// writeBit appends a single bit
+func (w *bitWriter) writeBit(bit bool) {
+	if 0 == w.n%8 {
+		w.data = append(w.data, 0)
+	}
+
+	if bit {
+		w.data[len(w.data)-1] |= 0x80 >> (w.n % 8)
+	}
+	w.n++
+}
+
+// writeBits appends the lowest n bits of v from the most significant one
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for i := n; i > 0; i-- {
+		w.writeBit(0 != (v>>(i-1))&0x01)
+	}
+}
+
+// bitReader reads bits in the most-significant-first order
+type bitReader struct {
+	data []byte
+	// n is the number of bits read
+	n uint
+}
+
+// readBit reads a single bit
+func (r *bitReader) readBit() (bool, error) {
+	if r.n >= uint(len(r.data))<<3 {
+		return false, io.ErrUnexpectedEOF
+	}
+
+	bit := 0 != r.data[r.n>>3]&(0x80>>(r.n%8))
+	r.n++
+
+	return bit, nil
+}
+
+// readBits reads n bits as the lowest bits of the output
+func (r *bitReader) readBits(n uint) (uint64, error) {
+	var v uint64
+	for i := uint(0); i < n; i++ {
+		bit, err := r.readBit()
+		if nil != err {
+			return 0, err
+		}
+
+		v <<= 1
+		if bit {
+			v |= 0x01
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,90 @@
+package gcs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/gcs/builder"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/gcs"
+)
+
+// compatBlock makes a block covering the corner cases of BIP158, i.e. empty
+// scripts, OP_RETURN outputs, witness spends and duplicate scripts
+func compatBlock() *btcwire.MsgBlock {
+	p2pkh := bip37.Unhexlify("76a91499108ad8ed9bb6274d3980bab5a85c048f0950c888ac")
+	p2wpkh := bip37.Unhexlify("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	opReturn := bip37.Unhexlify("6a0b68656c6c6f20776f726c64")
+
+	coinbase := btcwire.NewMsgTx(btcwire.TxVersion)
+	coinbase.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{}, 0xffffffff),
+		[]byte{0x51}, nil))
+	coinbase.AddTxOut(btcwire.NewTxOut(50, p2pkh))
+	coinbase.AddTxOut(btcwire.NewTxOut(0, opReturn))
+
+	// a segwit spend paying to an empty script and a duplicate script
+	spend := btcwire.NewMsgTx(btcwire.TxVersion)
+	spend.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{0x01}, 0), nil,
+		btcwire.TxWitness{bytes.Repeat([]byte{0x30}, 71), bytes.Repeat([]byte{0x02}, 33)}))
+	spend.AddTxOut(btcwire.NewTxOut(10, nil))
+	spend.AddTxOut(btcwire.NewTxOut(20, p2pkh))
+	spend.AddTxOut(btcwire.NewTxOut(30, p2wpkh))
+
+	block := btcwire.NewMsgBlock(btcwire.NewBlockHeader(1, &chainhash.Hash{0x02},
+		&chainhash.Hash{0x03}, 0x207fffff, 0))
+	block.AddTransaction(coinbase)
+	block.AddTransaction(spend)
+
+	return block
+}
+
+// TestBuildBasic_btcutil checks the filters and headers against the
+// independent BIP158 implementation of btcutil
+func TestBuildBasic_btcutil(t *testing.T) {
+	p2wpkh := bip37.Unhexlify("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+
+	testCases := []struct {
+		desc        string
+		block       *btcwire.MsgBlock
+		prevScripts [][]byte
+	}{
+		{"mainnet block", bip37.ReadBlock(t), nil},
+		{"mainnet block with prev scripts", bip37.ReadBlock(t),
+			[][]byte{bip37.Unhexlify("76a91499108ad8ed9bb6274d3980bab5a85c048f0950c888ac")}},
+		{"corner cases", compatBlock(), nil},
+		{"empty and duplicate prev scripts", compatBlock(),
+			[][]byte{nil, p2wpkh, p2wpkh, bip37.Unhexlify("a914f1d2a8a0e4b5c2a5e7b0a1f3c1e8d2a4b6c8e0f287")}},
+	}
+
+	var prevHeader chainhash.Hash
+	for i, c := range testCases {
+		expect, err := builder.BuildBasicFilter(c.block, c.prevScripts)
+		if nil != err {
+			t.Fatal(err)
+		}
+		expectBytes, err := expect.NBytes()
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		got := gcs.BuildBasic(c.block, c.prevScripts)
+		if !bytes.Equal(got.Bytes(), expectBytes) {
+			t.Fatalf("#%d [%s] invalid filter: got %x, expect %x", i, c.desc,
+				got.Bytes(), expectBytes)
+		}
+
+		expectHeader, err := builder.MakeHeaderForFilter(expect, prevHeader)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		filterHash := got.Hash()
+		if header := gcs.FilterHeader(&filterHash, &prevHeader); header != expectHeader {
+			t.Fatalf("#%d [%s] invalid header: got %v, expect %v", i, c.desc,
+				header, expectHeader)
+		}
+		prevHeader = expectHeader
+	}
+}
@@ -0,0 +1,15 @@
+package gcs
+
+import "errors"
+
+// ErrInvalidFilter signals the serialized filter is malformed
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ErrCheckpointMismatch signals a filter header conflicts with a checkpoint
+var ErrCheckpointMismatch = errors.New("filter header mismatches checkpoint")
+
+// ErrFilterTypeMismatch signals messages of different filter types are mixed
+var ErrFilterTypeMismatch = errors.New("filter type mismatch")
+
+// ErrHeaderMismatch signals a filter doesn't hash into the filter header
+var ErrHeaderMismatch = errors.New("filter header mismatch")
@@ -0,0 +1,179 @@
+// Package gcs implements the Golomb-coded set (GCS) compact block filters of
+// BIP158, which serves as the successor of the bloom filters of BIP37.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0158.mediawiki
+package gcs
+
+import (
+	"bytes"
+	"math/bits"
+	"sort"
+
+	"github.com/aead/siphash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Parameters of the basic filter type as BIP158 specifies
+const (
+	// P is the bit parameter of the Golomb-Rice coding
+	P = 19
+	// M is the inverse of the target false positive rate
+	M = 784931
+	// KeySize is the length of the SipHash key in bytes
+	KeySize = 16
+)
+
+// Filter is a Golomb-coded set of N items
+type Filter struct {
+	n uint32
+	p uint8
+	m uint64
+	// data is the Golomb-Rice coded deltas without the N prefix
+	data []byte
+}
+
+// N returns the number of items in the set
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+// Bytes serializes the filter as `N (CompactSize) || compressed set`
+func (f *Filter) Bytes() []byte {
+	var buf bytes.Buffer
+	// writing to bytes.Buffer never fails
+	wire.WriteVarInt(&buf, 0, uint64(f.n))
+	buf.Write(f.data)
+
+	return buf.Bytes()
+}
+
+// Match checks if the item is possibly in the set
+func (f *Filter) Match(key [KeySize]byte, item []byte) bool {
+	return f.MatchAny(key, [][]byte{item})
+}
+
+// MatchAny checks if any of the items is possibly in the set, which decodes
+// the set only once
+func (f *Filter) MatchAny(key [KeySize]byte, items [][]byte) bool {
+	if 0 == f.n || 0 == len(items) {
+		return false
+	}
+
+	queries := hashItems(key, items, uint64(f.n)*f.m)
+	sort.Sort(uint64s(queries))
+
+	r := &bitReader{data: f.data}
+	var value uint64
+	for i, j := uint32(0), 0; i < f.n; i++ {
+		delta, err := golombDecode(r, f.p)
+		if nil != err {
+			return false
+		}
+		value += delta
+
+		for ; j < len(queries) && queries[j] < value; j++ {
+		}
+		if j == len(queries) {
+			return false
+		} else if queries[j] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Build makes a filter of the given items keyed by key, where duplicated
+// items are taken only once
+func Build(key [KeySize]byte, items [][]byte, p uint8, m uint64) *Filter {
+	seen := make(map[string]bool, len(items))
+	unique := make([][]byte, 0, len(items))
+	for _, v := range items {
+		if !seen[string(v)] {
+			seen[string(v)] = true
+			unique = append(unique, v)
+		}
+	}
+
+	f := &Filter{n: uint32(len(unique)), p: p, m: m}
+
+	values := hashItems(key, unique, uint64(f.n)*m)
+	sort.Sort(uint64s(values))
+
+	w := new(bitWriter)
+	var last uint64
+	for _, v := range values {
+		golombEncode(w, v-last, p)
+		last = v
+	}
+	f.data = w.data
+
+	return f
+}
+
+// FromBytes deserializes a filter serialized by Filter.Bytes
+func FromBytes(data []byte, p uint8, m uint64) (*Filter, error) {
+	r := bytes.NewReader(data)
+
+	n, err := wire.ReadVarInt(r, 0)
+	if nil != err || n > uint64(^uint32(0)) {
+		return nil, ErrInvalidFilter
+	}
+
+	return &Filter{
+		n:    uint32(n),
+		p:    p,
+		m:    m,
+		data: append([]byte(nil), data[len(data)-r.Len():]...),
+	}, nil
+}
+
+// golombDecode reads a value coded by golombEncode
+func golombDecode(r *bitReader, p uint8) (uint64, error) {
+	var q uint64
+	for {
+		bit, err := r.readBit()
+		if nil != err {
+			return 0, err
+		} else if !bit {
+			break
+		}
+		q++
+	}
+
+	rem, err := r.readBits(uint(p))
+	if nil != err {
+		return 0, err
+	}
+
+	return (q << p) | rem, nil
+}
+
+// golombEncode writes x as the quotient x>>p in unary followed by the lowest
+// p bits
+func golombEncode(w *bitWriter, x uint64, p uint8) {
+	for q := x >> p; q > 0; q-- {
+		w.writeBit(true)
+	}
+	w.writeBit(false)
+
+	w.writeBits(x, uint(p))
+}
+
+// hashItems maps each item into [0, F) by SipHash-2-4 keyed by key, where the
+// 64-bit hash is scaled to the range by multiplying and taking the upper
+// 64 bits
+func hashItems(key [KeySize]byte, items [][]byte, F uint64) []uint64 {
+	out := make([]uint64, len(items))
+	for i, v := range items {
+		out[i], _ = bits.Mul64(siphash.Sum64(v, &key), F)
+	}
+
+	return out
+}
+
+// uint64s implements sort.Interface for []uint64
+type uint64s []uint64
+
+func (s uint64s) Len() int           { return len(s) }
+func (s uint64s) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint64s) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,163 @@
+package gcs_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/gcs"
+)
+
+// vector is a row of the BIP158 test vectors
+type vector struct {
+	height      int
+	blockHash   string
+	block       *btcwire.MsgBlock
+	prevScripts [][]byte
+	prevHeader  string
+	filter      []byte
+	header      string
+	notes       string
+}
+
+// readVectors reads in the BIP158 test vectors from the testdata, which is
+// the official bip-0158/testnet-19.json
+func readVectors(t *testing.T) []*vector {
+	var rows [][]interface{}
+	bip37.ReadTestData(t, "bip158.json", &rows)
+
+	var out []*vector
+	// skip the leading header row
+	for _, row := range rows[1:] {
+		v := &vector{
+			height:     int(row[0].(float64)),
+			blockHash:  row[1].(string),
+			block:      new(btcwire.MsgBlock),
+			prevHeader: row[4].(string),
+			filter:     bip37.Unhexlify(row[5].(string)),
+			header:     row[6].(string),
+			notes:      row[7].(string),
+		}
+
+		if err := v.block.Deserialize(bytes.NewReader(bip37.Unhexlify(row[2].(string)))); nil != err {
+			t.Fatal(err)
+		}
+
+		for _, s := range row[3].([]interface{}) {
+			v.prevScripts = append(v.prevScripts, bip37.Unhexlify(s.(string)))
+		}
+
+		out = append(out, v)
+	}
+
+	return out
+}
+
+func TestBuildBasic(t *testing.T) {
+	for _, v := range readVectors(t) {
+		if got := v.block.BlockHash().String(); got != v.blockHash {
+			t.Fatalf("%d [%s] invalid block hash: got %s, expect %s", v.height,
+				v.notes, got, v.blockHash)
+		}
+
+		filter := gcs.BuildBasic(v.block, v.prevScripts)
+		if got := filter.Bytes(); !bytes.Equal(got, v.filter) {
+			t.Fatalf("%d [%s] invalid filter: got %x, expect %x", v.height,
+				v.notes, got, v.filter)
+		}
+	}
+}
+
+func TestBasicFromBytes(t *testing.T) {
+	for _, v := range readVectors(t) {
+		filter, err := gcs.BasicFromBytes(v.filter)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		if got := filter.Bytes(); !bytes.Equal(got, v.filter) {
+			t.Fatalf("%d [%s] invalid re-serialization: got %x, expect %x",
+				v.height, v.notes, got, v.filter)
+		}
+
+		hash := v.block.BlockHash()
+		key := gcs.Key(&hash)
+		for _, tx := range v.block.Transactions {
+			for _, out := range tx.TxOut {
+				// empty and OP_RETURN scripts are left out of basic filters
+				if 0 == len(out.PkScript) || txscript.OP_RETURN == out.PkScript[0] {
+					continue
+				}
+
+				if !filter.Match(key, out.PkScript) {
+					t.Fatalf("%d [%s] failed to match %x", v.height, v.notes,
+						out.PkScript)
+				}
+			}
+		}
+	}
+
+	if _, err := gcs.BasicFromBytes(nil); gcs.ErrInvalidFilter != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, gcs.ErrInvalidFilter)
+	}
+}
+
+func TestFilter_MatchAny(t *testing.T) {
+	block := bip37.ReadBlock(t)
+	prevScripts := [][]byte{bip37.Unhexlify("76a91499108ad8ed9bb6274d3980bab5a85c048f0950c888ac")}
+
+	filter := gcs.BuildBasic(block, prevScripts)
+
+	hash := block.BlockHash()
+	key := gcs.Key(&hash)
+
+	var scripts [][]byte
+	for _, tx := range block.Transactions {
+		for _, out := range tx.TxOut {
+			scripts = append(scripts, out.PkScript)
+		}
+	}
+
+	unique := make(map[string]bool)
+	for _, v := range append(scripts, prevScripts...) {
+		unique[string(v)] = true
+	}
+	if got, expect := filter.N(), uint32(len(unique)); got != expect {
+		t.Fatalf("invalid N: got %d, expect %d", got, expect)
+	}
+
+	absent := [][]byte{[]byte("hello"), []byte("world")}
+	testCases := []struct {
+		items  [][]byte
+		expect bool
+	}{
+		{scripts, true},
+		{prevScripts, true},
+		{append(absent, scripts[len(scripts)-1]), true},
+		{absent, false},
+		{nil, false},
+	}
+
+	for i, c := range testCases {
+		if got := filter.MatchAny(key, c.items); got != c.expect {
+			t.Fatalf("#%d invalid matching status: got %v, expect %v", i, got,
+				c.expect)
+		}
+	}
+}
+
+func TestBuild_empty(t *testing.T) {
+	var key [gcs.KeySize]byte
+
+	filter := gcs.Build(key, nil, gcs.P, gcs.M)
+	if got := hex.EncodeToString(filter.Bytes()); got != "00" {
+		t.Fatalf("invalid empty filter: got %s, expect 00", got)
+	}
+
+	if filter.Match(key, []byte("hello")) {
+		t.Fatal("empty filter should match nothing")
+	}
+}
@@ -0,0 +1,91 @@
+package gcs
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/sammyne/bip37/wire"
+)
+
+// Hash calculates the filter hash as the double SHA256 of its serialization
+func (f *Filter) Hash() chainhash.Hash {
+	return chainhash.DoubleHashH(f.Bytes())
+}
+
+// FilterHeader chains the filter hash onto the previous filter header as
+// BIP157 specifies, i.e. double SHA256 of `filterHash||prevHeader`
+func FilterHeader(filterHash, prevHeader *chainhash.Hash) chainhash.Hash {
+	var buf [2 * chainhash.HashSize]byte
+	copy(buf[:], filterHash[:])
+	copy(buf[chainhash.HashSize:], prevHeader[:])
+
+	return chainhash.DoubleHashH(buf[:])
+}
+
+// FilterHeaders chains the filter hashes of a cfheaders message into the
+// filter headers of the corresponding blocks
+func FilterHeaders(msg *wire.CFHeaders) []chainhash.Hash {
+	headers := make([]chainhash.Hash, len(msg.FilterHashes))
+
+	prev := msg.PrevFilterHeader
+	for i := range msg.FilterHashes {
+		headers[i] = FilterHeader(&msg.FilterHashes[i], &prev)
+		prev = headers[i]
+	}
+
+	return headers
+}
+
+// VerifyCFHeaders chains the filter hashes of msg, whose first one is of the
+// block at startHeight, and checks the resulting filter headers against the
+// checkpoints falling into [startHeight-1, startHeight+len(FilterHashes)).
+// The verified filter headers are returned.
+func VerifyCFHeaders(msg *wire.CFHeaders, startHeight uint32,
+	checkpt *wire.CFCheckpt) ([]chainhash.Hash, error) {
+	if msg.FilterType != checkpt.FilterType {
+		return nil, ErrFilterTypeMismatch
+	}
+
+	headers := FilterHeaders(msg)
+
+	check := func(height uint32, header *chainhash.Hash) error {
+		if 0 == height || 0 != height%wire.CFCheckptInterval {
+			return nil
+		}
+
+		i := int(height/wire.CFCheckptInterval) - 1
+		if i >= len(checkpt.FilterHeaders) {
+			return nil
+		}
+
+		if !checkpt.FilterHeaders[i].IsEqual(header) {
+			return ErrCheckpointMismatch
+		}
+
+		return nil
+	}
+
+	if startHeight > 0 {
+		if err := check(startHeight-1, &msg.PrevFilterHeader); nil != err {
+			return nil, err
+		}
+	}
+
+	for i := range headers {
+		if err := check(startHeight+uint32(i), &headers[i]); nil != err {
+			return nil, err
+		}
+	}
+
+	return headers, nil
+}
+
+// VerifyCFilter checks if the filter carried by msg hashes into the filter
+// header given the previous one
+func VerifyCFilter(msg *wire.CFilter, prevHeader,
+	header *chainhash.Hash) error {
+	filterHash := chainhash.DoubleHashH(msg.Filter)
+	if got := FilterHeader(&filterHash, prevHeader); !got.IsEqual(header) {
+		return ErrHeaderMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package gcs_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/sammyne/bip37/gcs"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestFilterHeader(t *testing.T) {
+	vectors := readVectors(t)
+	for i, v := range vectors {
+		// headers of consecutive blocks chain up
+		if 0 != i && vectors[i-1].height+1 == v.height &&
+			vectors[i-1].header != v.prevHeader {
+			t.Fatalf("%d [%s] broken chain: got %s, expect %s", v.height, v.notes,
+				v.prevHeader, vectors[i-1].header)
+		}
+
+		prev, err := chainhash.NewHashFromStr(v.prevHeader)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		filter, err := gcs.BasicFromBytes(v.filter)
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		filterHash := filter.Hash()
+		if got := gcs.FilterHeader(&filterHash, prev); got.String() != v.header {
+			t.Fatalf("%d [%s] invalid header: got %s, expect %s", v.height,
+				v.notes, got, v.header)
+		}
+	}
+}
+
+// fakeCFHeaders makes the cfheaders of n blocks from genesis with distinct
+// filter hashes, together with the checkpoints over them
+func fakeCFHeaders(n int) (*wire.CFHeaders, *wire.CFCheckpt) {
+	msg := &wire.CFHeaders{FilterHashes: make([]chainhash.Hash, n)}
+	for i := range msg.FilterHashes {
+		msg.FilterHashes[i] = chainhash.DoubleHashH([]byte{byte(i), byte(i >> 8)})
+	}
+
+	checkpt := new(wire.CFCheckpt)
+	for i, h := range gcs.FilterHeaders(msg) {
+		if 0 != i && 0 == i%wire.CFCheckptInterval {
+			checkpt.FilterHeaders = append(checkpt.FilterHeaders, h)
+		}
+	}
+
+	return msg, checkpt
+}
+
+func TestVerifyCFHeaders(t *testing.T) {
+	all, checkpt := fakeCFHeaders(2500)
+	headers := gcs.FilterHeaders(all)
+
+	// verify the batch of [1500, 2500), which crosses the 2nd checkpoint
+	msg := &wire.CFHeaders{
+		PrevFilterHeader: headers[1499],
+		FilterHashes:     all.FilterHashes[1500:],
+	}
+
+	got, err := gcs.VerifyCFHeaders(msg, 1500, checkpt)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	for i := range got {
+		if !got[i].IsEqual(&headers[1500+i]) {
+			t.Fatalf("#%d invalid header: got %s, expect %s", i, got[i],
+				headers[1500+i])
+		}
+	}
+
+	// the batch starting right after a checkpoint checks the previous header
+	msg = &wire.CFHeaders{
+		PrevFilterHeader: headers[999],
+		FilterHashes:     all.FilterHashes[1000:1001],
+	}
+	if _, err := gcs.VerifyCFHeaders(msg, 1001, checkpt); gcs.ErrCheckpointMismatch != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, gcs.ErrCheckpointMismatch)
+	}
+}
+
+func TestVerifyCFHeaders_errors(t *testing.T) {
+	all, checkpt := fakeCFHeaders(1200)
+
+	tampered := &wire.CFHeaders{
+		FilterHashes: append([]chainhash.Hash(nil), all.FilterHashes...),
+	}
+	tampered.FilterHashes[500][0] ^= 0x01
+
+	if _, err := gcs.VerifyCFHeaders(tampered, 0, checkpt); gcs.ErrCheckpointMismatch != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, gcs.ErrCheckpointMismatch)
+	}
+
+	otherType := &wire.CFCheckpt{FilterType: wire.FilterTypeBasic + 1}
+	if _, err := gcs.VerifyCFHeaders(all, 0, otherType); gcs.ErrFilterTypeMismatch != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, gcs.ErrFilterTypeMismatch)
+	}
+}
+
+func TestVerifyCFilter(t *testing.T) {
+	for _, v := range readVectors(t) {
+		prev, _ := chainhash.NewHashFromStr(v.prevHeader)
+		header, _ := chainhash.NewHashFromStr(v.header)
+
+		msg := &wire.CFilter{BlockHash: v.block.BlockHash(), Filter: v.filter}
+		if err := gcs.VerifyCFilter(msg, prev, header); nil != err {
+			t.Fatalf("%d [%s] unexpected error: %v", v.height, v.notes, err)
+		}
+
+		msg.Filter = append([]byte(nil), v.filter...)
+		msg.Filter[len(msg.Filter)-1] ^= 0x01
+		if err := gcs.VerifyCFilter(msg, prev, header); gcs.ErrHeaderMismatch != err {
+			t.Fatalf("%d [%s] invalid error: got %v, expect %v", v.height, v.notes,
+				err, gcs.ErrHeaderMismatch)
+		}
+	}
+}
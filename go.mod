@@ -1,6 +1,7 @@
 module github.com/sammyne/bip37
 
 require (
+	github.com/aead/siphash v1.0.1
 	github.com/btcsuite/btcd v0.0.0-20190213025234-306aecffea32
 	github.com/btcsuite/btcutil v0.0.0-20190207003914-4c204d697803
 	github.com/sammyne/murmur3 v0.0.0-20190312003036-78c34e474254
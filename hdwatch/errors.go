@@ -0,0 +1,6 @@
+package hdwatch
+
+import "errors"
+
+// ErrInvalidBranch signals the branch is neither External nor Change
+var ErrInvalidBranch = errors.New("invalid branch")
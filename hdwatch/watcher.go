@@ -0,0 +1,322 @@
+// Package hdwatch keeps a bloom filter ahead of the addresses used by an HD
+// wallet, which derives addresses from an extended public key as specified by
+// BIP32, BIP44 or BIP84 and watches up to a gap limit of unused addresses on
+// both the external and change branches.
+package hdwatch
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/sammyne/bip37/bloom"
+)
+
+// DefaultGapLimit is the gap limit recommended by BIP44
+const DefaultGapLimit = 20
+
+// Branches of the account key as specified by BIP44
+const (
+	External uint32 = 0
+	Change   uint32 = 1
+)
+
+// Scheme enumerates the supported derivation schemes, which decide the type
+// of the derived addresses
+type Scheme uint8
+
+// Enumerations of supported schemes
+const (
+	// BIP32 derives P2PKH addresses as BIP44 does
+	BIP32 Scheme = iota
+	// BIP44 derives P2PKH addresses
+	BIP44
+	// BIP84 derives P2WPKH addresses
+	BIP84
+)
+
+// Config configures a watcher
+type Config struct {
+	// Scheme is the derivation scheme of the account key
+	Scheme Scheme
+	// GapLimit is the number of unused addresses to watch on each branch,
+	// which defaults to DefaultGapLimit if 0
+	GapLimit uint32
+	// MaxFPRate is the threshold of FP rate passed to bloom.Diff when
+	// extending the window
+	MaxFPRate float64
+	// Net is the network of derived addresses, which defaults to the main
+	// network if nil
+	Net *chaincfg.Params
+}
+
+// Path locates a derived key under the account key
+type Path struct {
+	Branch uint32
+	Index  uint32
+}
+
+// Watcher maintains a filter holding the public keys and their HASH160 of
+// addresses derived from an account key. Whenever a matched tx pays to an
+// address within the gap limit of the last derived one, more addresses are
+// derived and the corresponding filter update is emitted. It is safe for
+// concurrent use.
+type Watcher struct {
+	mtx sync.Mutex
+
+	config   Config
+	branches [2]*hdkeychain.ExtendedKey
+	filter   *bloom.Filter
+
+	// derived is the number of derived keys of each branch
+	derived [2]uint32
+	// used is the number of keys of each branch up to the last used one
+	used [2]uint32
+	// paths indexes derived keys by their public key and HASH160
+	paths map[string]Path
+	// elements is all elements added into the filter in order, including
+	// OutPoints added by the filter itself
+	elements [][]byte
+}
+
+// New makes a watcher over the account key, which is an extended key of
+// m/purpose'/coin'/account' and neutered if private. The first gap-limit
+// addresses of both branches are added into filter.
+func New(key string, filter *bloom.Filter, config Config) (*Watcher, error) {
+	if 0 == config.GapLimit {
+		config.GapLimit = DefaultGapLimit
+	}
+	if nil == config.Net {
+		config.Net = &chaincfg.MainNetParams
+	}
+
+	account, err := hdkeychain.NewKeyFromString(key)
+	if nil != err {
+		return nil, err
+	}
+
+	if account, err = account.Neuter(); nil != err {
+		return nil, err
+	}
+
+	w := &Watcher{config: config, filter: filter, paths: make(map[string]Path)}
+	for _, b := range []uint32{External, Change} {
+		if w.branches[b], err = account.Child(b); nil != err {
+			return nil, err
+		}
+	}
+
+	var derivations []*derivation
+	for _, b := range []uint32{External, Change} {
+		d, err := w.derive(b, 0, config.GapLimit)
+		if nil != err {
+			return nil, err
+		}
+		derivations = append(derivations, d)
+	}
+
+	for _, d := range derivations {
+		for _, v := range d.elements {
+			if err := filter.Add(v); nil != err {
+				return nil, err
+			}
+		}
+		w.apply(d)
+		w.elements = append(w.elements, d.elements...)
+	}
+
+	return w, nil
+}
+
+// Address returns the address at the given path, whose type is decided by
+// the scheme
+func (w *Watcher) Address(path Path) (btcutil.Address, error) {
+	if path.Branch > Change {
+		return nil, ErrInvalidBranch
+	}
+
+	key, err := w.branches[path.Branch].Child(path.Index)
+	if nil != err {
+		return nil, err
+	}
+
+	pubKey, err := key.ECPubKey()
+	if nil != err {
+		return nil, err
+	}
+
+	hash := btcutil.Hash160(pubKey.SerializeCompressed())
+	if BIP84 == w.config.Scheme {
+		return btcutil.NewAddressWitnessPubKeyHash(hash, w.config.Net)
+	}
+
+	return btcutil.NewAddressPubKeyHash(hash, w.config.Net)
+}
+
+// Derived returns the number of derived addresses of the branch
+func (w *Watcher) Derived(branch uint32) (uint32, error) {
+	if branch > Change {
+		return 0, ErrInvalidBranch
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.derived[branch], nil
+}
+
+// Filter returns the maintained filter
+func (w *Watcher) Filter() *bloom.Filter {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.filter
+}
+
+// MatchTxAndUpdate matches tx against the filter as bloom.Filter does. If tx
+// pays to a derived address within the gap limit of the last derived one, the
+// window is extended and the filter update to send is returned, which is nil
+// otherwise.
+func (w *Watcher) MatchTxAndUpdate(tx *btcutil.Tx) (bool, *bloom.Update,
+	error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	ok, tracked := w.filter.MatchTxAndTrack(tx)
+	for i := range tracked {
+		w.elements = append(w.elements, bloom.OutPointElement(&tracked[i]))
+	}
+
+	if !ok {
+		return false, nil, nil
+	}
+
+	// mark the used addresses, which is committed along with the derived keys
+	// only if all steps below succeed
+	used := w.used
+	elems := bloom.ExtractElements(tx)
+	for _, out := range elems.Outputs {
+		for _, v := range out.Pushes {
+			if path, found := w.paths[string(v)]; found && path.Index >= used[path.Branch] {
+				used[path.Branch] = path.Index + 1
+			}
+		}
+	}
+
+	before := w.elements
+	var (
+		after       [][]byte
+		derivations []*derivation
+	)
+	for _, b := range []uint32{External, Change} {
+		want := used[b] + w.config.GapLimit
+		if want <= w.derived[b] {
+			continue
+		}
+
+		d, err := w.derive(b, w.derived[b], want-w.derived[b])
+		if nil != err {
+			return ok, nil, err
+		}
+		after = append(after, d.elements...)
+		derivations = append(derivations, d)
+	}
+
+	if 0 == len(after) {
+		w.used = used
+		return ok, nil, nil
+	}
+	after = append(append([][]byte(nil), before...), after...)
+
+	update, err := bloom.Diff(w.filter, before, after, w.config.MaxFPRate)
+	if nil != err {
+		return ok, nil, err
+	}
+
+	w.used = used
+	for _, d := range derivations {
+		w.apply(d)
+	}
+
+	if nil != update.Load {
+		w.filter.Recover(update.Load)
+	}
+	for _, v := range update.Adds {
+		w.filter.Add(v.Data)
+	}
+	w.elements = after
+
+	return ok, update, nil
+}
+
+// Used returns the number of addresses of the branch up to the last used one
+func (w *Watcher) Used(branch uint32) (uint32, error) {
+	if branch > Change {
+		return 0, ErrInvalidBranch
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.used[branch], nil
+}
+
+// derivation is the keys derived for a branch but not yet committed into the
+// watcher
+type derivation struct {
+	branch uint32
+	// next is the number of derived keys of the branch once committed
+	next uint32
+	// elements is the public keys and their HASH160 in order
+	elements [][]byte
+	paths    map[string]Path
+}
+
+// apply commits the derivation d into w
+func (w *Watcher) apply(d *derivation) {
+	w.derived[d.branch] = d.next
+	for k, v := range d.paths {
+		w.paths[k] = v
+	}
+}
+
+// derive derives n keys of the branch starting from the index from, and
+// returns their public keys and HASH160 as filter elements without touching
+// the state of w
+func (w *Watcher) derive(branch, from, n uint32) (*derivation, error) {
+	d := &derivation{
+		branch:   branch,
+		next:     from,
+		elements: make([][]byte, 0, 2*n),
+		paths:    make(map[string]Path, 2*n),
+	}
+	for uint32(len(d.elements)) < 2*n {
+		path := Path{Branch: branch, Index: d.next}
+
+		key, err := w.branches[branch].Child(path.Index)
+		if hdkeychain.ErrInvalidChild == err {
+			// skip the invalid child as BIP32 specifies
+			d.next++
+			continue
+		} else if nil != err {
+			return nil, err
+		}
+
+		pubKey, err := key.ECPubKey()
+		if nil != err {
+			return nil, err
+		}
+
+		serialized := pubKey.SerializeCompressed()
+		hash := btcutil.Hash160(serialized)
+
+		d.paths[string(serialized)] = path
+		d.paths[string(hash)] = path
+		d.elements = append(d.elements, serialized, hash)
+
+		d.next++
+	}
+
+	return d, nil
+}
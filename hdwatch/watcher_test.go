@@ -0,0 +1,235 @@
+package hdwatch_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/hdwatch"
+	"github.com/sammyne/bip37/wire"
+)
+
+// xpubBIP32 is the master public key of test vector 1 of BIP32
+const xpubBIP32 = "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+
+// accountKey derives the account key m/purpose'/0'/0' from the seed of test
+// vector 1 of BIP32
+func accountKey(t *testing.T, purpose uint32) string {
+	master, err := hdkeychain.NewMaster(
+		bip37.Unhexlify("000102030405060708090a0b0c0d0e0f"), &chaincfg.MainNetParams)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	key := master
+	for _, i := range []uint32{purpose, 0, 0} {
+		if key, err = key.Child(hdkeychain.HardenedKeyStart + i); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	return key.String()
+}
+
+// payTo makes a tx paying to the address at path of w
+func payTo(t *testing.T, w *hdwatch.Watcher, path hdwatch.Path) *btcutil.Tx {
+	addr, err := w.Address(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	tx := btcwire.NewMsgTx(btcwire.TxVersion)
+	tx.AddTxIn(btcwire.NewTxIn(bip37.NewOutPoint(make([]byte, 32), path.Index),
+		nil, nil))
+	tx.AddTxOut(btcwire.NewTxOut(1000, pkScript))
+
+	return btcutil.NewTx(tx)
+}
+
+// derived returns the number of derived addresses of the branch of w
+func derived(t *testing.T, w *hdwatch.Watcher, branch uint32) uint32 {
+	n, err := w.Derived(branch)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+// used returns the number of used addresses of the branch of w
+func used(t *testing.T, w *hdwatch.Watcher, branch uint32) uint32 {
+	n, err := w.Used(branch)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestNew(t *testing.T) {
+	const gapLimit = 5
+
+	filter := bloom.New(4*gapLimit, 0.0001, wire.UpdateAll, bloom.Tweak)
+
+	w, err := hdwatch.New(xpubBIP32, filter, hdwatch.Config{GapLimit: gapLimit})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	for _, b := range []uint32{hdwatch.External, hdwatch.Change} {
+		if got := derived(t, w, b); got != gapLimit {
+			t.Fatalf("branch %d: invalid #(derived): got %d, expect %d", b, got,
+				gapLimit)
+		}
+
+		for i := uint32(0); i < gapLimit; i++ {
+			if ok := w.Filter().MatchTx(payTo(t, w, hdwatch.Path{b, i})); !ok {
+				t.Fatalf("address %d/%d isn't watched", b, i)
+			}
+		}
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	filter := bloom.New(10, 0.0001, wire.UpdateAll, bloom.Tweak)
+	if _, err := hdwatch.New("xpub-invalid", filter, hdwatch.Config{}); nil == err {
+		t.Fatal("invalid key should trigger error")
+	}
+
+	filter.Clear()
+	if _, err := hdwatch.New(xpubBIP32, filter, hdwatch.Config{}); bloom.ErrUninitialised != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, bloom.ErrUninitialised)
+	}
+}
+
+func TestWatcher_MatchTxAndUpdate(t *testing.T) {
+	const gapLimit = 5
+
+	filter := bloom.New(16*gapLimit, 0.0001, wire.UpdateAll, bloom.Tweak)
+
+	w, err := hdwatch.New(accountKey(t, 44), filter,
+		hdwatch.Config{Scheme: hdwatch.BIP44, GapLimit: gapLimit, MaxFPRate: 0.01})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	first := hdwatch.Path{hdwatch.External, 0}
+
+	// using the first address keeps gapLimit unused ones by 1 more key
+	ok, update, err := w.MatchTxAndUpdate(payTo(t, w, first))
+	if nil != err {
+		t.Fatal(err)
+	} else if !ok || nil == update || 2 != len(update.Adds) {
+		t.Fatalf("unexpected status: ok=%v, update=%v", ok, update)
+	}
+
+	// reusing an address changes nothing
+	ok, update, err = w.MatchTxAndUpdate(payTo(t, w, first))
+	if nil != err {
+		t.Fatal(err)
+	} else if !ok || nil != update {
+		t.Fatalf("unexpected status: ok=%v, update=%v", ok, update)
+	}
+
+	// using an address near the edge extends the window
+	edge := hdwatch.Path{hdwatch.External, gapLimit - 2}
+	ok, update, err = w.MatchTxAndUpdate(payTo(t, w, edge))
+	if nil != err {
+		t.Fatal(err)
+	} else if !ok || nil == update {
+		t.Fatalf("unexpected status: ok=%v, update=%v", ok, update)
+	}
+
+	// 3 more keys of 2 elements each, unless reloading is cheaper
+	if nil == update.Load && len(update.Adds) != 2*(gapLimit-2) {
+		t.Fatalf("invalid #(filteradd): got %d, expect %d", len(update.Adds),
+			2*(gapLimit-2))
+	}
+
+	if got, expect := used(t, w, hdwatch.External), edge.Index+1; got != expect {
+		t.Fatalf("invalid #(used): got %d, expect %d", got, expect)
+	}
+	if got, expect := derived(t, w, hdwatch.External), edge.Index+1+gapLimit; got != expect {
+		t.Fatalf("invalid #(derived): got %d, expect %d", got, expect)
+	}
+	if got := derived(t, w, hdwatch.Change); got != gapLimit {
+		t.Fatalf("change branch shouldn't be extended: got %d", got)
+	}
+
+	last := hdwatch.Path{hdwatch.External, derived(t, w, hdwatch.External) - 1}
+	if !w.Filter().MatchTx(payTo(t, w, last)) {
+		t.Fatal("the extended address isn't watched")
+	}
+}
+
+func TestWatcher_MatchTxAndUpdate_bip84(t *testing.T) {
+	const gapLimit = 3
+
+	filter := bloom.New(8*gapLimit, 0.0001, wire.UpdateAll, bloom.Tweak)
+
+	w, err := hdwatch.New(accountKey(t, 84), filter,
+		hdwatch.Config{Scheme: hdwatch.BIP84, GapLimit: gapLimit, MaxFPRate: 0.01})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	path := hdwatch.Path{hdwatch.Change, gapLimit - 1}
+
+	addr, err := w.Address(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, ok := addr.(*btcutil.AddressWitnessPubKeyHash); !ok {
+		t.Fatalf("invalid address type: %T", addr)
+	}
+
+	tx := payTo(t, w, path)
+	ok, update, err := w.MatchTxAndUpdate(tx)
+	if nil != err {
+		t.Fatal(err)
+	} else if !ok || nil == update {
+		t.Fatalf("unexpected status: ok=%v, update=%v", ok, update)
+	}
+
+	if got, expect := derived(t, w, hdwatch.Change), path.Index+1+gapLimit; got != expect {
+		t.Fatalf("invalid #(derived): got %d, expect %d", got, expect)
+	}
+
+	// the OutPoint added by UpdateAll survives the update
+	if !w.Filter().MatchOutPoint(btcwire.NewOutPoint(tx.Hash(), 0)) {
+		t.Fatal("the OutPoint of the matched output is lost")
+	}
+}
+
+func TestWatcher_invalidBranch(t *testing.T) {
+	filter := bloom.New(20, 0.0001, wire.UpdateNone, bloom.Tweak)
+
+	w, err := hdwatch.New(xpubBIP32, filter, hdwatch.Config{GapLimit: 1})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	const branch = hdwatch.Change + 1
+	if _, err := w.Address(hdwatch.Path{branch, 0}); hdwatch.ErrInvalidBranch != err {
+		t.Fatalf("invalid error of Address: got %v, expect %v", err,
+			hdwatch.ErrInvalidBranch)
+	}
+	if _, err := w.Derived(branch); hdwatch.ErrInvalidBranch != err {
+		t.Fatalf("invalid error of Derived: got %v, expect %v", err,
+			hdwatch.ErrInvalidBranch)
+	}
+	if _, err := w.Used(branch); hdwatch.ErrInvalidBranch != err {
+		t.Fatalf("invalid error of Used: got %v, expect %v", err,
+			hdwatch.ErrInvalidBranch)
+	}
+}
@@ -0,0 +1,260 @@
+// Package headers implements a header chain for SPV clients, which connects
+// headers by proof of work, follows the chain of the most cumulative work and
+// checks merkle blocks against it.
+package headers
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37/merkle"
+)
+
+// Node is a connected header
+type Node struct {
+	Header btcwire.BlockHeader
+	Hash   chainhash.Hash
+	Height uint32
+	// Work is the cumulative work of the chain ending at this header
+	Work *big.Int
+
+	parent *Node
+}
+
+// Parent returns the parent node, which is nil for the genesis
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// TipUpdate describes how the best chain changes by connecting a header
+type TipUpdate struct {
+	// Detached is the headers leaving the best chain from the old tip down
+	Detached []*Node
+	// Attached is the headers joining the best chain up to the new tip
+	Attached []*Node
+}
+
+// IsReorg tells if any header leaves the best chain
+func (u *TipUpdate) IsReorg() bool {
+	return len(u.Detached) > 0
+}
+
+// Chain is a header chain following the most cumulative work. Only the proof
+// of work of each header is checked against its bits and the network limit,
+// whereas difficulty retargeting isn't enforced. It is safe for concurrent
+// use.
+type Chain struct {
+	mtx sync.RWMutex
+
+	params *chaincfg.Params
+	store  Store
+	nodes  map[chainhash.Hash]*Node
+	// best is the best chain indexed by height
+	best []*Node
+}
+
+// Best returns the tip of the best chain
+func (c *Chain) Best() *Node {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.best[len(c.best)-1]
+}
+
+// Confirmations returns the number of confirmations of the header, i.e. 1 for
+// the tip, which is 0 if it isn't on the best chain
+func (c *Chain) Confirmations(hash *chainhash.Hash) uint32 {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	node, ok := c.nodes[*hash]
+	if !ok || !c.onBestChain(node) {
+		return 0
+	}
+
+	return uint32(len(c.best)) - node.Height
+}
+
+// Connect checks and connects the header, and persists it to the store. The
+// update of the best chain is returned, which is nil if the best chain stays.
+// Known headers are ignored.
+func (c *Chain) Connect(header *btcwire.BlockHeader) (*TipUpdate, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	hash := header.BlockHash()
+	if _, ok := c.nodes[hash]; ok {
+		return nil, nil
+	}
+
+	node, err := c.newNode(header)
+	if nil != err {
+		return nil, err
+	}
+
+	if err := c.store.Append(header); nil != err {
+		return nil, err
+	}
+
+	return c.connect(node), nil
+}
+
+// IsOnBestChain tells if the header is on the best chain
+func (c *Chain) IsOnBestChain(hash *chainhash.Hash) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	node, ok := c.nodes[*hash]
+	return ok && c.onBestChain(node)
+}
+
+// NodeByHash returns the connected header of hash, which is on any branch
+func (c *Chain) NodeByHash(hash *chainhash.Hash) (*Node, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	node, ok := c.nodes[*hash]
+	return node, ok
+}
+
+// NodeByHeight returns the header at height of the best chain
+func (c *Chain) NodeByHeight(height uint32) (*Node, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if height >= uint32(len(c.best)) {
+		return nil, false
+	}
+
+	return c.best[height], true
+}
+
+// VerifyMerkleBlock verifies the merkle block, and checks its header is on the
+// best chain. The matched txids and the header node are returned if ok.
+func (c *Chain) VerifyMerkleBlock(msg *btcwire.MsgMerkleBlock) (
+	[]*chainhash.Hash, *Node, error) {
+	matched, ok := merkle.Parse(msg)
+	if !ok {
+		return nil, nil, ErrInvalidMerkleBlock
+	}
+
+	hash := msg.Header.BlockHash()
+
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	node, ok := c.nodes[hash]
+	if !ok || !c.onBestChain(node) {
+		return nil, nil, ErrNotOnBestChain
+	}
+
+	return matched, node, nil
+}
+
+// connect links the checked node into the index and switches the best chain
+// to it if it carries more work
+func (c *Chain) connect(node *Node) *TipUpdate {
+	c.nodes[node.Hash] = node
+
+	tip := c.best[len(c.best)-1]
+	if node.Work.Cmp(tip.Work) <= 0 {
+		return nil
+	}
+
+	update := new(TipUpdate)
+
+	// walk back from the new tip to the fork point
+	fork := node
+	for ; !c.onBestChain(fork); fork = fork.parent {
+		update.Attached = append(update.Attached, fork)
+	}
+	for i, j := 0, len(update.Attached)-1; i < j; i, j = i+1, j-1 {
+		update.Attached[i], update.Attached[j] = update.Attached[j], update.Attached[i]
+	}
+
+	for i := len(c.best) - 1; i > int(fork.Height); i-- {
+		update.Detached = append(update.Detached, c.best[i])
+	}
+
+	c.best = append(c.best[:fork.Height+1], update.Attached...)
+
+	return update
+}
+
+// newNode checks the proof of work of header and makes its node
+func (c *Chain) newNode(header *btcwire.BlockHeader) (*Node, error) {
+	parent, ok := c.nodes[header.PrevBlock]
+	if !ok {
+		return nil, ErrOrphan
+	}
+
+	hash := header.BlockHash()
+	target := blockchain.CompactToBig(header.Bits)
+	if target.Sign() <= 0 || target.Cmp(c.params.PowLimit) > 0 ||
+		blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return nil, ErrInvalidPoW
+	}
+
+	node := &Node{
+		Header: *header,
+		Hash:   hash,
+		Height: parent.Height + 1,
+		Work:   new(big.Int).Add(parent.Work, blockchain.CalcWork(header.Bits)),
+		parent: parent,
+	}
+
+	return node, nil
+}
+
+func (c *Chain) onBestChain(node *Node) bool {
+	return node.Height < uint32(len(c.best)) && c.best[node.Height] == node
+}
+
+// New makes a header chain of the network specified by params, which is
+// rebuilt from the headers in store. The genesis header is appended to an
+// empty store.
+func New(params *chaincfg.Params, store Store) (*Chain, error) {
+	headers, err := store.Headers()
+	if nil != err {
+		return nil, err
+	}
+
+	genesis := params.GenesisBlock.Header
+	if 0 == len(headers) {
+		if err := store.Append(&genesis); nil != err {
+			return nil, err
+		}
+		headers = []btcwire.BlockHeader{genesis}
+	}
+
+	if headers[0].BlockHash() != *params.GenesisHash {
+		return nil, ErrGenesisMismatch
+	}
+
+	root := &Node{
+		Header: genesis,
+		Hash:   *params.GenesisHash,
+		Work:   blockchain.CalcWork(genesis.Bits),
+	}
+
+	c := &Chain{
+		params: params,
+		store:  store,
+		nodes:  map[chainhash.Hash]*Node{root.Hash: root},
+		best:   []*Node{root},
+	}
+
+	for i := 1; i < len(headers); i++ {
+		node, err := c.newNode(&headers[i])
+		if nil != err {
+			return nil, ErrCorruptStore
+		}
+		c.connect(node)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,342 @@
+package headers_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/headers"
+	"github.com/sammyne/bip37/merkle"
+	"github.com/sammyne/bip37/wire"
+)
+
+var params = &chaincfg.RegressionNetParams
+
+// mine makes a header extending parent with the given merkle root, which
+// passes the proof of work of regtest
+func mine(parent *btcwire.BlockHeader, root chainhash.Hash) *btcwire.BlockHeader {
+	header := &btcwire.BlockHeader{
+		Version:    1,
+		PrevBlock:  parent.BlockHash(),
+		MerkleRoot: root,
+		Timestamp:  parent.Timestamp.Add(10 * time.Minute),
+		Bits:       params.PowLimitBits,
+	}
+
+	target := blockchain.CompactToBig(header.Bits)
+	for {
+		hash := header.BlockHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			return header
+		}
+		header.Nonce++
+	}
+}
+
+// mineChain makes n headers extending parent, where salt distinguishes
+// branches
+func mineChain(parent *btcwire.BlockHeader, n int,
+	salt string) []*btcwire.BlockHeader {
+	out := make([]*btcwire.BlockHeader, n)
+	for i := range out {
+		out[i] = mine(parent, chainhash.HashH([]byte(salt+string(rune('0'+i)))))
+		parent = out[i]
+	}
+
+	return out
+}
+
+func connectAll(t *testing.T, chain *headers.Chain,
+	hs []*btcwire.BlockHeader) []*headers.TipUpdate {
+	updates := make([]*headers.TipUpdate, len(hs))
+	for i, h := range hs {
+		update, err := chain.Connect(h)
+		if nil != err {
+			t.Fatalf("#%d failed to connect: %v", i, err)
+		}
+		updates[i] = update
+	}
+
+	return updates
+}
+
+func TestChain_Connect(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	main := mineChain(&params.GenesisBlock.Header, 3, "main")
+	for i, update := range connectAll(t, chain, main) {
+		if nil == update || update.IsReorg() || 1 != len(update.Attached) ||
+			update.Attached[0].Hash != main[i].BlockHash() {
+			t.Fatalf("#%d invalid update: %+v", i, update)
+		}
+	}
+
+	best := chain.Best()
+	if 3 != best.Height || best.Hash != main[2].BlockHash() {
+		t.Fatalf("invalid tip: height %d, hash %v", best.Height, best.Hash)
+	}
+
+	hash := main[0].BlockHash()
+	if got := chain.Confirmations(&hash); 3 != got {
+		t.Fatalf("invalid #(confirmations): got %d, expect 3", got)
+	}
+
+	// known headers are ignored
+	if update, err := chain.Connect(main[1]); nil != update || nil != err {
+		t.Fatalf("unexpected update: %+v, %v", update, err)
+	}
+}
+
+func TestChain_Connect_reorg(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	main := mineChain(&params.GenesisBlock.Header, 2, "main")
+	side := mineChain(&params.GenesisBlock.Header, 3, "side")
+
+	connectAll(t, chain, main)
+	updates := connectAll(t, chain, side)
+
+	// the first seen chain wins ties
+	if nil != updates[0] || nil != updates[1] {
+		t.Fatalf("unexpected updates before side chain wins: %+v", updates[:2])
+	}
+
+	update := updates[2]
+	if nil == update || !update.IsReorg() {
+		t.Fatalf("reorg is expected: %+v", update)
+	}
+
+	if 2 != len(update.Detached) || update.Detached[0].Hash != main[1].BlockHash() ||
+		update.Detached[1].Hash != main[0].BlockHash() {
+		t.Fatal("invalid detached headers")
+	}
+
+	if 3 != len(update.Attached) {
+		t.Fatalf("invalid #(attached): got %d, expect 3", len(update.Attached))
+	}
+	for i, node := range update.Attached {
+		if node.Hash != side[i].BlockHash() {
+			t.Fatalf("invalid attached[%d]: got %v", i, node.Hash)
+		}
+	}
+
+	for _, h := range main {
+		hash := h.BlockHash()
+		if chain.IsOnBestChain(&hash) || 0 != chain.Confirmations(&hash) {
+			t.Fatalf("%v should leave the best chain", hash)
+		}
+		if _, ok := chain.NodeByHash(&hash); !ok {
+			t.Fatalf("%v should stay known", hash)
+		}
+	}
+
+	if node, ok := chain.NodeByHeight(1); !ok || node.Hash != side[0].BlockHash() {
+		t.Fatal("invalid header at height 1")
+	}
+}
+
+func TestChain_Connect_invalid(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	hs := mineChain(&params.GenesisBlock.Header, 2, "main")
+
+	badPoW := *hs[0]
+	for {
+		hash := badPoW.BlockHash()
+		if blockchain.HashToBig(&hash).Cmp(blockchain.CompactToBig(badPoW.Bits)) > 0 {
+			break
+		}
+		badPoW.Nonce++
+	}
+
+	easier := *hs[0]
+	easier.Bits = 0x2100ffff
+
+	testCases := []struct {
+		header *btcwire.BlockHeader
+		expect error
+	}{
+		{hs[1], headers.ErrOrphan},
+		{&badPoW, headers.ErrInvalidPoW},
+		{&easier, headers.ErrInvalidPoW},
+	}
+
+	for i, c := range testCases {
+		if _, err := chain.Connect(c.header); c.expect != err {
+			t.Fatalf("#%d invalid error: got %v, expect %v", i, err, c.expect)
+		}
+	}
+
+	if 0 != chain.Best().Height {
+		t.Fatal("invalid headers shouldn't be connected")
+	}
+}
+
+func TestChain_VerifyMerkleBlock(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	coinbase := btcwire.NewMsgTx(btcwire.TxVersion)
+	coinbase.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{}, 0xffffffff), []byte{0x51, 0x51}, nil))
+	coinbase.AddTxOut(btcwire.NewTxOut(50, []byte{0x51}))
+
+	block := btcwire.NewMsgBlock(mine(&params.GenesisBlock.Header, coinbase.TxHash()))
+	block.AddTransaction(coinbase)
+
+	filter := bloom.New(10, 0.000001, wire.UpdateNone, bloom.Tweak)
+	h := coinbase.TxHash()
+	filter.Add(h[:])
+
+	merkleBlock, _ := merkle.New(block, filter)
+
+	if _, _, err := chain.VerifyMerkleBlock(merkleBlock); headers.ErrNotOnBestChain != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, headers.ErrNotOnBestChain)
+	}
+
+	connectAll(t, chain, []*btcwire.BlockHeader{&block.Header})
+
+	matched, node, err := chain.VerifyMerkleBlock(merkleBlock)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(matched) || *matched[0] != h || 1 != node.Height {
+		t.Fatalf("invalid result: %v at %d", matched, node.Height)
+	}
+
+	tampered := *merkleBlock
+	tampered.Hashes = []*chainhash.Hash{{0x01}}
+	if _, _, err := chain.VerifyMerkleBlock(&tampered); headers.ErrInvalidMerkleBlock != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, headers.ErrInvalidMerkleBlock)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headers")
+
+	store, err := headers.OpenFileStore(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	chain, err := headers.New(params, store)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	main := mineChain(&params.GenesisBlock.Header, 2, "main")
+	side := mineChain(&params.GenesisBlock.Header, 3, "side")
+	connectAll(t, chain, main)
+	connectAll(t, chain, side)
+	expect := chain.Best().Hash
+
+	if err := store.Close(); nil != err {
+		t.Fatal(err)
+	}
+
+	// simulate a crash in the middle of appending
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if nil != err {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0x01, 0x02, 0x03})
+	f.Close()
+
+	if store, err = headers.OpenFileStore(path); nil != err {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if chain, err = headers.New(params, store); nil != err {
+		t.Fatal(err)
+	}
+
+	if got := chain.Best(); 3 != got.Height || got.Hash != expect {
+		t.Fatalf("invalid tip: height %d, hash %v", got.Height, got.Hash)
+	}
+
+	next := mine(side[2], chainhash.HashH([]byte("next")))
+	connectAll(t, chain, []*btcwire.BlockHeader{next})
+
+	hs, err := store.Headers()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 7 != len(hs) || hs[6].BlockHash() != next.BlockHash() {
+		t.Fatalf("invalid stored headers: %d", len(hs))
+	}
+}
+
+func TestNew_genesisMismatch(t *testing.T) {
+	store := new(headers.MemStore)
+	store.Append(&chaincfg.MainNetParams.GenesisBlock.Header)
+
+	if _, err := headers.New(params, store); headers.ErrGenesisMismatch != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, headers.ErrGenesisMismatch)
+	}
+}
+
+// a 7-tx block matching txs 4 and 6 takes exactly 8 flag bits, which fill up
+// a whole byte
+func TestChain_VerifyMerkleBlock_wholeFlagBytes(t *testing.T) {
+	chain, err := headers.New(params, new(headers.MemStore))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	txs := make([]*btcutil.Tx, 7)
+	for i := range txs {
+		tx := btcwire.NewMsgTx(btcwire.TxVersion)
+		tx.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{}, 0xffffffff), []byte{0x51, byte(i)}, nil))
+		tx.AddTxOut(btcwire.NewTxOut(50, []byte{0x51}))
+		txs[i] = btcutil.NewTx(tx)
+	}
+	merkles := blockchain.BuildMerkleTreeStore(txs, false)
+
+	block := btcwire.NewMsgBlock(mine(&params.GenesisBlock.Header, *merkles[len(merkles)-1]))
+	for _, tx := range txs {
+		block.AddTransaction(tx.MsgTx())
+	}
+	connectAll(t, chain, []*btcwire.BlockHeader{&block.Header})
+
+	filter := bloom.New(10, 0.000001, wire.UpdateNone, bloom.Tweak)
+	for _, i := range []int{4, 6} {
+		filter.Add(txs[i].Hash()[:])
+	}
+
+	merkleBlock, _ := merkle.New(block, filter)
+	if 1 != len(merkleBlock.Flags) {
+		t.Fatalf("the fixture should take a whole flag byte: %x", merkleBlock.Flags)
+	}
+
+	matched, _, err := chain.VerifyMerkleBlock(merkleBlock)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(matched) || *matched[0] != *txs[4].Hash() || *matched[1] != *txs[6].Hash() {
+		t.Fatalf("invalid matched txids: %v", matched)
+	}
+
+	tampered := *merkleBlock
+	tampered.Hashes = append([]*chainhash.Hash{{0x01}}, merkleBlock.Hashes[1:]...)
+	if _, _, err := chain.VerifyMerkleBlock(&tampered); headers.ErrInvalidMerkleBlock != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, headers.ErrInvalidMerkleBlock)
+	}
+}
@@ -0,0 +1,21 @@
+package headers
+
+import "errors"
+
+// ErrOrphan signals the parent of the header is unknown
+var ErrOrphan = errors.New("orphan header")
+
+// ErrInvalidPoW signals the header fails the proof of work check
+var ErrInvalidPoW = errors.New("invalid proof of work")
+
+// ErrGenesisMismatch signals the store holds headers of another network
+var ErrGenesisMismatch = errors.New("genesis header mismatch")
+
+// ErrCorruptStore signals the stored headers fail to connect
+var ErrCorruptStore = errors.New("corrupt header store")
+
+// ErrInvalidMerkleBlock signals the merkle block fails to verify
+var ErrInvalidMerkleBlock = errors.New("invalid merkle block")
+
+// ErrNotOnBestChain signals the header isn't on the best chain
+var ErrNotOnBestChain = errors.New("header not on the best chain")
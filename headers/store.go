@@ -0,0 +1,114 @@
+package headers
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+)
+
+// Store persists the connected headers, which are replayed by New to rebuild
+// the chain
+type Store interface {
+	// Headers returns all stored headers in the order of appending
+	Headers() ([]btcwire.BlockHeader, error)
+	// Append persists a newly connected header
+	Append(header *btcwire.BlockHeader) error
+}
+
+// MemStore keeps headers in memory
+type MemStore struct {
+	mtx     sync.Mutex
+	headers []btcwire.BlockHeader
+}
+
+// Append implements Store
+func (s *MemStore) Append(header *btcwire.BlockHeader) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.headers = append(s.headers, *header)
+
+	return nil
+}
+
+// Headers implements Store
+func (s *MemStore) Headers() ([]btcwire.BlockHeader, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return append([]btcwire.BlockHeader(nil), s.headers...), nil
+}
+
+// FileStore appends headers to a file as consecutive 80-byte records. A
+// partial record at the end, e.g. left by a crash, is discarded on reading.
+type FileStore struct {
+	mtx  sync.Mutex
+	file *os.File
+}
+
+// Append implements Store
+func (s *FileStore) Append(header *btcwire.BlockHeader) error {
+	var buf bytes.Buffer
+	if err := header.Serialize(&buf); nil != err {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the underlying file
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
+
+// Headers implements Store
+func (s *FileStore) Headers() ([]btcwire.BlockHeader, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.file.Seek(0, io.SeekStart); nil != err {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(s.file)
+	if nil != err {
+		return nil, err
+	}
+
+	n := len(data) / btcwire.MaxBlockHeaderPayload
+	headers := make([]btcwire.BlockHeader, n)
+	r := bytes.NewReader(data)
+	for i := range headers {
+		if err := headers[i].Deserialize(r); nil != err {
+			return nil, err
+		}
+	}
+
+	// drop the partial record so later appends stay aligned
+	if size := int64(n * btcwire.MaxBlockHeaderPayload); size != int64(len(data)) {
+		if err := s.file.Truncate(size); nil != err {
+			return nil, err
+		}
+	}
+
+	return headers, nil
+}
+
+// OpenFileStore opens the header file at path, which is created if not
+// existing
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if nil != err {
+		return nil, err
+	}
+
+	return &FileStore{file: file}, nil
+}
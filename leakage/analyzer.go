@@ -0,0 +1,176 @@
+// Package leakage plays the role of a curious full node, which intersects the
+// matches of several filters loaded by the same client to tell which elements
+// probably belong to the client, i.e. the "multiple filter intersection"
+// attack on BIP37.
+package leakage
+
+import (
+	"encoding/hex"
+	"sort"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// Candidate is an element of the local candidate set together with how it
+// matches the captured filters
+type Candidate struct {
+	// Element is the candidate element, e.g. a HASH160 of an address
+	Element []byte
+	// Hits is the number of filters matching the element
+	Hits int
+	// FPProbability is the probability that an element not owned by the
+	// client matches the same filters as this one, i.e. the product of FP
+	// rates of matching filters and the complements of FP rates of the others
+	FPProbability float64
+}
+
+// Probable checks if the candidate is matched by all n captured filters
+func (c *Candidate) Probable(n int) bool {
+	return c.Hits == n
+}
+
+// Report is the result of analyzing a candidate set
+type Report struct {
+	// Filters is the number of captured filters
+	Filters int
+	// FPRates is the estimated FP rate of each captured filter
+	FPRates []float64
+	// Tested is the number of distinct candidates tested
+	Tested int
+	// Candidates is the matched candidates sorted by Hits in descending and
+	// then FPProbability in ascending order
+	Candidates []*Candidate
+}
+
+// Probable returns the candidates matched by all captured filters
+func (r *Report) Probable() []*Candidate {
+	var out []*Candidate
+	for _, c := range r.Candidates {
+		if c.Probable(r.Filters) {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// ExpectedFalsePositives estimates how many of the tested candidates would
+// be matched by all filters without being owned by the client
+func (r *Report) ExpectedFalsePositives() float64 {
+	p := 1.0
+	for _, v := range r.FPRates {
+		p *= v
+	}
+
+	return p * float64(r.Tested)
+}
+
+// Analyzer intersects the matches of captured filters from the same client
+type Analyzer struct {
+	filters []*bloom.Filter
+	fpRates []float64
+}
+
+// FromSnapshots makes an analyzer based on the captured filterload messages,
+// which hash with the default C seed constant as BIP37 specifies
+func FromSnapshots(snapshots ...*wire.FilterLoad) *Analyzer {
+	filters := make([]*bloom.Filter, len(snapshots))
+	for i, v := range snapshots {
+		filters[i] = bloom.Load(v)
+	}
+
+	return New(filters...)
+}
+
+// New makes an analyzer based on the captured filters, which are copied so
+// that later updates to them won't affect the analysis
+func New(filters ...*bloom.Filter) *Analyzer {
+	a := &Analyzer{
+		filters: make([]*bloom.Filter, len(filters)),
+		fpRates: make([]float64, len(filters)),
+	}
+
+	for i, v := range filters {
+		a.filters[i] = v.Clone()
+		a.fpRates[i] = a.filters[i].EstimatedFPRate()
+	}
+
+	return a
+}
+
+// Elements analyzes the given candidate elements
+func (a *Analyzer) Elements(elements [][]byte) *Report {
+	report := &Report{
+		Filters: len(a.filters),
+		FPRates: append([]float64(nil), a.fpRates...),
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range elements {
+		key := hex.EncodeToString(v)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if c := a.match(v); nil != c {
+			report.Candidates = append(report.Candidates, c)
+		}
+	}
+	report.Tested = len(seen)
+
+	sort.SliceStable(report.Candidates, func(i, j int) bool {
+		x, y := report.Candidates[i], report.Candidates[j]
+		if x.Hits != y.Hits {
+			return x.Hits > y.Hits
+		}
+
+		return x.FPProbability < y.FPProbability
+	})
+
+	return report
+}
+
+// Blocks analyzes the elements pushed by the public key scripts of all txs
+// in the given blocks, which is what a full node sees as addresses
+func (a *Analyzer) Blocks(blocks ...*btcwire.MsgBlock) *Report {
+	return a.Elements(BlockElements(blocks...))
+}
+
+// BlockElements collects the elements pushed by the public key scripts of all
+// txs in the given blocks
+func BlockElements(blocks ...*btcwire.MsgBlock) [][]byte {
+	var elements [][]byte
+	for _, b := range blocks {
+		for _, tx := range b.Transactions {
+			elems := bloom.ExtractElements(btcutil.NewTx(tx))
+			for _, out := range elems.Outputs {
+				elements = append(elements, out.Pushes...)
+			}
+		}
+	}
+
+	return elements
+}
+
+// match tests v against all filters, and returns nil if no filter matches
+func (a *Analyzer) match(v []byte) *Candidate {
+	c := &Candidate{Element: v, FPProbability: 1}
+	for i, f := range a.filters {
+		if f.Match(v) {
+			c.Hits++
+			c.FPProbability *= a.fpRates[i]
+		} else {
+			c.FPProbability *= 1 - a.fpRates[i]
+		}
+	}
+
+	if 0 == c.Hits {
+		return nil
+	}
+
+	return c
+}
@@ -0,0 +1,144 @@
+package leakage_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/leakage"
+	"github.com/sammyne/bip37/wire"
+)
+
+// fakeElements generates n distinct 20-byte elements deterministically
+func fakeElements(prefix string, n int) [][]byte {
+	out := make([][]byte, n)
+	for i := range out {
+		h := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", prefix, i)))
+		out[i] = h[:20]
+	}
+
+	return out
+}
+
+func TestAnalyzer_Elements(t *testing.T) {
+	wallet := fakeElements("wallet", 8)
+	innocents := fakeElements("innocent", 2000)
+
+	// the same client loads filters of distinct tweaks with a loose FP rate
+	var snapshots []*wire.FilterLoad
+	for i := byte(0); i < 3; i++ {
+		f := bloom.New(uint32(len(wallet)), 0.05, wire.UpdateAll, uint32(i)+1)
+		for _, v := range wallet {
+			f.Add(v)
+		}
+
+		snapshots = append(snapshots, f.Snapshot())
+	}
+
+	report := leakage.FromSnapshots(snapshots...).Elements(append(innocents, wallet...))
+
+	if report.Tested != len(wallet)+len(innocents) {
+		t.Fatalf("invalid #(tested): got %d, expect %d", report.Tested,
+			len(wallet)+len(innocents))
+	}
+
+	probable := report.Probable()
+	for i, v := range wallet {
+		var found bool
+		for _, c := range probable {
+			found = found || bytes.Equal(c.Element, v)
+		}
+
+		if !found {
+			t.Fatalf("#%d wallet element isn't reported", i)
+		}
+	}
+
+	// the intersection should leave few innocents
+	if n := len(probable) - len(wallet); float64(n) > 1+3*report.ExpectedFalsePositives() {
+		t.Fatalf("too many innocents survive the intersection: %d, expect %f", n,
+			report.ExpectedFalsePositives())
+	}
+
+	// a single filter hides the wallet much better
+	single := leakage.FromSnapshots(snapshots[0]).Elements(append(innocents, wallet...))
+	if len(single.Probable()) <= len(probable) {
+		t.Fatalf("intersection should shrink the anonymity set: %d vs %d",
+			len(single.Probable()), len(probable))
+	}
+
+	for i := 1; i < len(report.Candidates); i++ {
+		if report.Candidates[i-1].Hits < report.Candidates[i].Hits {
+			t.Fatal("candidates aren't sorted by hits")
+		}
+	}
+}
+
+func TestAnalyzer_Blocks(t *testing.T) {
+	block := bip37.ReadBlock(t)
+
+	data, err := txscript.PushedData(block.Transactions[1].TxOut[0].PkScript)
+	if nil != err {
+		t.Fatal(err)
+	}
+	owned := data[len(data)-1]
+
+	var snapshots []*wire.FilterLoad
+	for i := uint32(0); i < 2; i++ {
+		f := bloom.New(1, 0.000001, wire.UpdateNone, i)
+		f.Add(owned)
+		snapshots = append(snapshots, f.Snapshot())
+	}
+
+	probable := leakage.FromSnapshots(snapshots...).Blocks(block).Probable()
+	if len(probable) != 1 || !bytes.Equal(probable[0].Element, owned) {
+		t.Fatalf("invalid probable elements: %v", probable)
+	}
+}
+
+func TestNew_customC(t *testing.T) {
+	owned := fakeElements("wallet", 1)[0]
+
+	// the C seed constant isn't carried by filterload messages
+	f := bloom.New(1, 0.000001, wire.UpdateNone, 1, 0x12345678)
+	f.Add(owned)
+
+	probable := leakage.New(f).Elements([][]byte{owned}).Probable()
+	if 1 != len(probable) {
+		t.Fatal("filter of a custom C should match its elements")
+	}
+
+	// updating the filter afterwards doesn't affect the analyzer
+	a := leakage.New(f)
+	f.Add([]byte("later"))
+	if 0 != len(a.Elements([][]byte{[]byte("later")}).Probable()) {
+		t.Fatal("analyzer should hold a copy of the filter")
+	}
+}
+
+func TestAnalyzer_Elements_fpProbability(t *testing.T) {
+	elements := fakeElements("wallet", 2)
+
+	var filters []*bloom.Filter
+	for i, v := range elements {
+		f := bloom.New(1, 0.000001, wire.UpdateNone, uint32(i)+1)
+		f.Add(v)
+		filters = append(filters, f)
+	}
+
+	report := leakage.New(filters...).Elements(elements[:1])
+	if 1 != len(report.Candidates) || 1 != report.Candidates[0].Hits {
+		t.Fatalf("the element should be matched by exactly 1 filter: %+v",
+			report.Candidates)
+	}
+
+	// matched by the 1st filter but not the 2nd
+	expect := report.FPRates[0] * (1 - report.FPRates[1])
+	if got := report.Candidates[0].FPProbability; got != expect {
+		t.Fatalf("invalid FP probability: got %g, expect %g", got, expect)
+	}
+}
@@ -140,6 +140,14 @@ func New(b *wire.MsgBlock, filter *bloom.Filter) (*wire.MsgMerkleBlock,
 // Parse validates if the given merkle block is valid, and returns the
 // matching hash if any
 func Parse(block *wire.MsgMerkleBlock) ([]*chainhash.Hash, bool) {
+	matched, _, ok := ParseWithIndices(block)
+	return matched, ok
+}
+
+// ParseWithIndices works as Parse but also returns the position of each
+// matching hash in the block
+func ParseWithIndices(block *wire.MsgMerkleBlock) ([]*chainhash.Hash,
+	[]uint32, bool) {
 	// calculate the tree height
 	var height uint32
 	for ; (1 << height) < block.Transactions; height++ {
@@ -148,17 +156,19 @@ func Parse(block *wire.MsgMerkleBlock) ([]*chainhash.Hash, bool) {
 	var (
 		j, k    int
 		matched []*chainhash.Hash
+		indices []uint32
 	)
 
-	root := parse(&matched, block, 0, height, &j, &k)
+	root := parse(&matched, &indices, block, 0, height, &j, &k)
 
 	// Check
 	//  - all hashes have been consumed
-	//  - all flag bits have been consumed
+	//  - all flag bits have been consumed, where the padding bits of the last
+	//    byte must be 0 unless no bit is left in it
 	//  - the merkle root matches
 	ok := len(block.Hashes) == k &&
 		len(block.Flags) == (j+7)/8 &&
-		0 == (block.Flags[j>>3]>>uint(j%8)) &&
+		(0 == j%8 || 0 == (block.Flags[j>>3]>>uint(j%8))) &&
 		block.Header.MerkleRoot.IsEqual(root)
 
 		//fmt.Println(len(block.Hashes) == k)
@@ -166,7 +176,7 @@ func Parse(block *wire.MsgMerkleBlock) ([]*chainhash.Hash, bool) {
 	//fmt.Println(0 == (block.Flags[j>>3] >> uint(j%8)))
 	// check the PoW
 
-	return matched, ok
+	return matched, indices, ok
 }
 
 // calcTreeWidth calculates the number of nodes at height for a tree
@@ -177,8 +187,8 @@ func calcTreeWidth(nTx, height uint32) uint32 {
 
 // j is the #(flag-bit) consumed
 // k is the #(hash) consumed
-func parse(matched *[]*chainhash.Hash, block *wire.MsgMerkleBlock,
-	i, height uint32, j, k *int) *chainhash.Hash {
+func parse(matched *[]*chainhash.Hash, indices *[]uint32,
+	block *wire.MsgMerkleBlock, i, height uint32, j, k *int) *chainhash.Hash {
 	if (*j>>3) >= len(block.Flags) || *k >= len(block.Hashes) {
 		// flag bits or hash list is exhausted
 		return nil
@@ -195,12 +205,13 @@ func parse(matched *[]*chainhash.Hash, block *wire.MsgMerkleBlock,
 		hash := block.Hashes[*k]
 		*k++
 		*matched = append(*matched, hash)
+		*indices = append(*indices, i)
 
 		return hash
 	}
 
 	childIdx := i << 1
-	L := parse(matched, block, childIdx, height-1, j, k)
+	L := parse(matched, indices, block, childIdx, height-1, j, k)
 	if nil == L {
 		return nil
 	}
@@ -211,7 +222,7 @@ func parse(matched *[]*chainhash.Hash, block *wire.MsgMerkleBlock,
 		return blockchain.HashMerkleBranches(L, L)
 	}
 
-	R := parse(matched, block, childIdx, height-1, j, k)
+	R := parse(matched, indices, block, childIdx, height-1, j, k)
 	if nil != R && !R.IsEqual(L) {
 		return blockchain.HashMerkleBranches(L, R)
 	}
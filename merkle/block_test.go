@@ -425,6 +425,34 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseWithIndices(t *testing.T) {
+	msg := bip37.ReadBlock(t)
+
+	bf := bloom.New(10, 0.000001, wire.UpdateNone)
+	included := []uint32{1, 3, 6}
+	for _, j := range included {
+		h := msg.Transactions[j].TxHash()
+		bf.Add(h[:])
+	}
+
+	block, hits := merkle.New(msg, bf)
+
+	matched, indices, ok := merkle.ParseWithIndices(block)
+	if !ok {
+		t.Fatal("failed to parse merkle block")
+	}
+
+	if !reflect.DeepEqual(indices, hits) {
+		t.Fatalf("invalid indices: got %v, expect %v", indices, hits)
+	}
+	for i, j := range indices {
+		if h := msg.Transactions[j].TxHash(); !h.IsEqual(matched[i]) {
+			t.Fatalf("invalid matched txid at %d: got %v, expect %v", j,
+				matched[i], h)
+		}
+	}
+}
+
 func TestParse_errors(t *testing.T) {
 	msg := bip37.ReadBlock(t)
 
@@ -495,3 +523,44 @@ func TestParse_errors(t *testing.T) {
 		}
 	}
 }
+
+// every subset of matched txs must parse, including those whose flag bits
+// fill up whole bytes
+func TestParse_allSubsets(t *testing.T) {
+	msg := bip37.ReadBlock(t)
+
+	nTx := len(msg.Transactions)
+	for mask := 0; mask < 1<<uint(nTx); mask++ {
+		bf := bloom.New(uint32(nTx), 0.000001, wire.UpdateNone)
+
+		var included []uint32
+		for i := 0; i < nTx; i++ {
+			if 0 != mask&(1<<uint(i)) {
+				h := msg.Transactions[i].TxHash()
+				bf.Add(h[:])
+				included = append(included, uint32(i))
+			}
+		}
+
+		block, hits := merkle.New(msg, bf)
+
+		if _, ok := merkle.Parse(block); !ok {
+			t.Fatalf("#%b failed to parse merkle block of flags %x", mask,
+				block.Flags)
+		}
+
+		matched, indices, ok := merkle.ParseWithIndices(block)
+		if !ok {
+			t.Fatalf("#%b failed to parse merkle block with indices", mask)
+		}
+		if !reflect.DeepEqual(indices, hits) {
+			t.Fatalf("#%b invalid indices: got %v, expect %v", mask, indices, hits)
+		}
+		for i, j := range included {
+			if h := msg.Transactions[j].TxHash(); len(matched) <= i ||
+				!h.IsEqual(matched[i]) {
+				t.Fatalf("#%b tx %d isn't matched", mask, j)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/merkle"
+)
+
+// KnownInventory tells what inventory a peer is known to have, e.g. the txs
+// already announced to or received from it
+type KnownInventory interface {
+	HasInventory(inv *btcwire.InvVect) bool
+}
+
+// FilteredBlock answers a getdata of MSG_FILTERED_BLOCK for block with the
+// messages to send in order, i.e. the merkleblock followed by every matched tx
+// not in known. The filter is updated by matched txs. A nil known skips no tx.
+// Nothing is sent if the peer has no filter loaded, as Bitcoin Core does.
+func FilteredBlock(block *btcwire.MsgBlock, filter *bloom.Filter,
+	known KnownInventory) []btcwire.Message {
+	if nil == filter || !filter.Loaded() {
+		return nil
+	}
+
+	merkleBlock, hits := merkle.New(block, filter)
+
+	out := make([]btcwire.Message, 1, 1+len(hits))
+	out[0] = merkleBlock
+	for _, i := range hits {
+		tx := block.Transactions[i]
+
+		h := tx.TxHash()
+		if nil != known && known.HasInventory(btcwire.NewInvVect(btcwire.InvTypeTx, &h)) {
+			continue
+		}
+
+		out = append(out, tx)
+	}
+
+	return out
+}
@@ -0,0 +1,89 @@
+package server_test
+
+import (
+	"testing"
+
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/sammyne/bip37"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/merkle"
+	"github.com/sammyne/bip37/server"
+	"github.com/sammyne/bip37/wire"
+)
+
+type knownInventory map[btcwire.InvVect]bool
+
+func (known knownInventory) HasInventory(inv *btcwire.InvVect) bool {
+	return known[*inv]
+}
+
+func TestFilteredBlock(t *testing.T) {
+	block := bip37.ReadBlock(t)
+
+	newFilter := func() *bloom.Filter {
+		filter := bloom.New(10, 0.000001, wire.UpdateAll, bloom.Tweak)
+		for _, i := range []int{1, 3} {
+			h := block.Transactions[i].TxHash()
+			filter.Add(h[:])
+		}
+
+		return filter
+	}
+
+	_, hits := merkle.New(block, newFilter())
+	if len(hits) < 2 {
+		t.Fatalf("too few hits to test: %v", hits)
+	}
+
+	// the peer has received the first matched tx already
+	skipped := block.Transactions[hits[0]].TxHash()
+	known := knownInventory{*btcwire.NewInvVect(btcwire.InvTypeTx, &skipped): true}
+
+	testCases := []struct {
+		known  server.KnownInventory
+		expect []uint32
+	}{
+		{nil, hits},
+		{known, hits[1:]},
+	}
+
+	for i, c := range testCases {
+		msgs := server.FilteredBlock(block, newFilter(), c.known)
+		if len(msgs) != 1+len(c.expect) {
+			t.Fatalf("#%d invalid #(msg): got %d, expect %d", i, len(msgs),
+				1+len(c.expect))
+		}
+
+		merkleBlock, ok := msgs[0].(*btcwire.MsgMerkleBlock)
+		if !ok {
+			t.Fatalf("#%d merkleblock should go first: got %T", i, msgs[0])
+		}
+
+		matched, ok := merkle.Parse(merkleBlock)
+		if !ok || len(matched) != len(hits) {
+			t.Fatalf("#%d invalid merkleblock: %d matched, ok=%v", i,
+				len(matched), ok)
+		}
+
+		for j, idx := range c.expect {
+			tx, ok := msgs[1+j].(*btcwire.MsgTx)
+			if !ok {
+				t.Fatalf("#%d invalid msg[%d]: got %T", i, 1+j, msgs[1+j])
+			}
+
+			if got, expect := tx.TxHash(), block.Transactions[idx].TxHash(); got != expect {
+				t.Fatalf("#%d invalid tx[%d]: got %v, expect %v", i, j, got, expect)
+			}
+		}
+	}
+}
+
+func TestFilteredBlock_unloaded(t *testing.T) {
+	block := bip37.ReadBlock(t)
+
+	for i, filter := range []*bloom.Filter{nil, new(bloom.Filter)} {
+		if msgs := server.FilteredBlock(block, filter, nil); 0 != len(msgs) {
+			t.Fatalf("#%d unexpected #(msg): %d", i, len(msgs))
+		}
+	}
+}
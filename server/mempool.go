@@ -0,0 +1,93 @@
+package server
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+)
+
+// Mempool provides a snapshot of the unconfirmed txs
+type Mempool interface {
+	// Transactions returns the txs in the pool in arbitrary order
+	Transactions() []*btcutil.Tx
+}
+
+// MempoolInv builds the inventory to answer a BIP35 mempool request of a peer
+// with filter. The txs are matched in dependency order by MatchTxAndUpdate,
+// so that unconfirmed spends of matched outputs are caught as well, and the
+// filter is updated accordingly. All txs are announced if filter is nil or
+// not loaded yet.
+// The returned inventory follows the dependency order, and would span multiple
+// inv messages if exceeding btcwire.MaxInvPerMsg.
+func MempoolInv(pool Mempool, filter *bloom.Filter) []*btcwire.InvVect {
+	txs := SortByDependency(pool.Transactions())
+	relayAll := nil == filter || !filter.Loaded()
+
+	inv := make([]*btcwire.InvVect, 0, len(txs))
+	for _, tx := range txs {
+		if relayAll || filter.MatchTxAndUpdate(tx) {
+			inv = append(inv, btcwire.NewInvVect(btcwire.InvTypeTx, tx.Hash()))
+		}
+	}
+
+	return inv
+}
+
+// SortByDependency orders txs so that every tx comes after the txs it spends
+// within txs. Ties are broken by the order of txs, so the result is
+// deterministic.
+func SortByDependency(txs []*btcutil.Tx) []*btcutil.Tx {
+	index := make(map[chainhash.Hash]int, len(txs))
+	for i, tx := range txs {
+		index[*tx.Hash()] = i
+	}
+
+	// nParents[i] counts the unsorted in-pool parents of txs[i], and
+	// children[i] lists the txs spending txs[i]
+	nParents := make([]int, len(txs))
+	children := make([][]int, len(txs))
+	for i, tx := range txs {
+		seen := make(map[int]bool)
+		for _, in := range tx.MsgTx().TxIn {
+			j, ok := index[in.PreviousOutPoint.Hash]
+			if !ok || seen[j] || j == i {
+				continue
+			}
+			seen[j] = true
+
+			nParents[i]++
+			children[j] = append(children[j], i)
+		}
+	}
+
+	out := make([]*btcutil.Tx, 0, len(txs))
+	done := make([]bool, len(txs))
+
+	var visit func(i int)
+	visit = func(i int) {
+		done[i] = true
+		out = append(out, txs[i])
+
+		for _, j := range children[i] {
+			if nParents[j]--; 0 == nParents[j] {
+				visit(j)
+			}
+		}
+	}
+
+	for i := range txs {
+		if !done[i] && 0 == nParents[i] {
+			visit(i)
+		}
+	}
+
+	// txs caught in a cycle can't be valid, but are kept to be faithful
+	for i := range txs {
+		if !done[i] {
+			out = append(out, txs[i])
+		}
+	}
+
+	return out
+}
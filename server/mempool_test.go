@@ -0,0 +1,137 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/server"
+	"github.com/sammyne/bip37/wire"
+)
+
+type mempool []*btcutil.Tx
+
+func (pool mempool) Transactions() []*btcutil.Tx {
+	return pool
+}
+
+// newTx makes a tx spending prev paying to the P2PKH script of keyHash
+func newTx(prev *btcwire.OutPoint, keyHash []byte) *btcutil.Tx {
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).AddData(keyHash).
+		AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+	if nil != err {
+		panic(err)
+	}
+
+	msg := btcwire.NewMsgTx(btcwire.TxVersion)
+	msg.AddTxIn(btcwire.NewTxIn(prev, nil, nil))
+	msg.AddTxOut(btcwire.NewTxOut(1000, pkScript))
+
+	return btcutil.NewTx(msg)
+}
+
+func keyHash(label string) []byte {
+	return btcutil.Hash160([]byte(label))
+}
+
+// newChain makes a chain of n unconfirmed txs, where the first one pays to
+// keyHash("root")
+func newChain(n int) []*btcutil.Tx {
+	confirmed := btcwire.NewOutPoint(&chainhash.Hash{0x01}, 0)
+
+	chain := []*btcutil.Tx{newTx(confirmed, keyHash("root"))}
+	for i := 1; i < n; i++ {
+		prev := btcwire.NewOutPoint(chain[i-1].Hash(), 0)
+		chain = append(chain, newTx(prev, keyHash(string(rune('a'+i)))))
+	}
+
+	return chain
+}
+
+func TestMempoolInv(t *testing.T) {
+	chain := newChain(4)
+	unrelated := newTx(btcwire.NewOutPoint(&chainhash.Hash{0x02}, 0),
+		keyHash("unrelated"))
+
+	// children are listed before their parents
+	pool := mempool{chain[3], unrelated, chain[2], chain[1], chain[0]}
+
+	testCases := []struct {
+		flags  wire.BloomUpdateType
+		expect []*btcutil.Tx
+	}{
+		// the spend of the matched output is caught by the updated OutPoint,
+		// whose own output isn't watched thus stops the chain
+		{wire.UpdateAll, chain[:2]},
+		// P2PKH outputs aren't updated
+		{wire.UpdateP2PubKeyOnly, chain[:1]},
+		{wire.UpdateNone, chain[:1]},
+	}
+
+	for i, c := range testCases {
+		filter := bloom.New(10, 0.000001, c.flags, bloom.Tweak)
+		filter.Add(keyHash("root"))
+
+		got := server.MempoolInv(pool, filter)
+		if len(got) != len(c.expect) {
+			t.Fatalf("#%d invalid #(inv): got %d, expect %d", i, len(got),
+				len(c.expect))
+		}
+
+		for j, v := range got {
+			if v.Type != btcwire.InvTypeTx || v.Hash != *c.expect[j].Hash() {
+				t.Fatalf("#%d invalid inv[%d]: got %v, expect %v", i, j, v.Hash,
+					c.expect[j].Hash())
+			}
+		}
+	}
+}
+
+func TestMempoolInv_unloaded(t *testing.T) {
+	chain := newChain(3)
+	pool := mempool{chain[2], chain[0], chain[1]}
+
+	for i, filter := range []*bloom.Filter{nil, new(bloom.Filter)} {
+		got := server.MempoolInv(pool, filter)
+		if len(got) != len(chain) {
+			t.Fatalf("#%d invalid #(inv): got %d, expect %d", i, len(got),
+				len(chain))
+		}
+	}
+}
+
+func TestSortByDependency(t *testing.T) {
+	chain := newChain(5)
+
+	// a tx spending both chain[4] and chain[1]
+	merge := btcwire.NewMsgTx(btcwire.TxVersion)
+	merge.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(chain[4].Hash(), 0), nil, nil))
+	merge.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(chain[1].Hash(), 0), nil, nil))
+
+	txs := []*btcutil.Tx{btcutil.NewTx(merge), chain[4], chain[2], chain[0],
+		chain[3], chain[1]}
+
+	sorted := server.SortByDependency(txs)
+	if len(sorted) != len(txs) {
+		t.Fatalf("invalid #(tx): got %d, expect %d", len(sorted), len(txs))
+	}
+
+	position := make(map[chainhash.Hash]int)
+	for i, tx := range sorted {
+		position[*tx.Hash()] = i
+	}
+
+	for _, tx := range sorted {
+		for _, in := range tx.MsgTx().TxIn {
+			j, ok := position[in.PreviousOutPoint.Hash]
+			if ok && j > position[*tx.Hash()] {
+				t.Fatalf("%v is sorted before its parent %v", tx.Hash(),
+					in.PreviousOutPoint.Hash)
+			}
+		}
+	}
+}
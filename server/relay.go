@@ -0,0 +1,127 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// Relay keeps the relay state of a peer, i.e. the fRelay flag of its version
+// message and its loaded filter, following the lifecycle of Bitcoin Core
+//  - filterload loads the filter and enables relay
+//  - filteradd updates the loaded filter
+//  - filterclear drops the filter but keeps relay on, so all txs are relayed
+// It is safe for concurrent use.
+type Relay struct {
+	// AllowNonStandardFlags opts in to filters of non-standard updating
+	// policies, which fall back to wire.UpdateNone otherwise. It should be
+	// set before loading any filter.
+	AllowNonStandardFlags bool
+
+	mtx    sync.RWMutex
+	relay  bool
+	filter *bloom.Filter
+}
+
+// Add takes the data of a filteradd into the loaded filter, which fails with
+// bloom.ErrUninitialised if no filter is loaded
+func (r *Relay) Add(msg *wire.FilterAdd) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if nil == r.filter {
+		return bloom.ErrUninitialised
+	}
+
+	return r.filter.Add(msg.Data)
+}
+
+// Clear drops the loaded filter as filterclear, and turns on relay
+func (r *Relay) Clear() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.relay, r.filter = true, nil
+}
+
+// Decide decides whether to relay each tx of txs to the peer in order,
+// updating the loaded filter by matched txs
+func (r *Relay) Decide(txs []*btcutil.Tx) []bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	out := make([]bool, len(txs))
+	for i, tx := range txs {
+		out[i] = r.shouldRelay(tx)
+	}
+
+	return out
+}
+
+// Filter returns the loaded filter, which would be nil if none
+func (r *Relay) Filter() *bloom.Filter {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.filter
+}
+
+// Load loads the filter of a filterload and turns on relay. As Bitcoin Core
+// does, filters beyond the size limits of BIP37 are rejected with
+// bloom.ErrInvalidEncoding, filters of empty bits match everything, and
+// unknown updating policies apply no update. So do non-standard policies
+// unless AllowNonStandardFlags is set.
+func (r *Relay) Load(msg *wire.FilterLoad) error {
+	if !withinSizeConstraints(msg) {
+		return bloom.ErrInvalidEncoding
+	}
+
+	snapshot := *msg
+	if !snapshot.Flags.IsValid() ||
+		(!snapshot.Flags.IsStandard() && !r.AllowNonStandardFlags) {
+		snapshot.Flags = wire.UpdateNone
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.relay, r.filter = true, bloom.Load(&snapshot)
+
+	return nil
+}
+
+// Relaying tells if txs are relayed to the peer at all
+func (r *Relay) Relaying() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.relay
+}
+
+// ShouldRelay decides whether to relay tx to the peer, updating the loaded
+// filter if tx matches
+func (r *Relay) ShouldRelay(tx *btcutil.Tx) bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.shouldRelay(tx)
+}
+
+func (r *Relay) shouldRelay(tx *btcutil.Tx) bool {
+	switch {
+	case !r.relay:
+		return false
+	case nil == r.filter:
+		return true
+	default:
+		return r.filter.MatchTxAndUpdate(tx)
+	}
+}
+
+// NewRelay makes the relay state of a peer given the fRelay flag of its
+// version message
+func NewRelay(relay bool) *Relay {
+	return &Relay{relay: relay}
+}
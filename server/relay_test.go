@@ -0,0 +1,128 @@
+package server_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/server"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestRelay_lifecycle(t *testing.T) {
+	chain := newChain(3)
+	unrelated := newTx(btcwire.NewOutPoint(&chainhash.Hash{0x02}, 0),
+		keyHash("unrelated"))
+	txs := []*btcutil.Tx{chain[0], unrelated, chain[1], chain[2]}
+
+	filter := bloom.New(10, 0.000001, wire.UpdateAll, bloom.Tweak)
+	filter.Add(keyHash("root"))
+
+	relay := server.NewRelay(false)
+	if relay.Relaying() {
+		t.Fatal("relay should be off by fRelay")
+	}
+	if got, expect := relay.Decide(txs), []bool{false, false, false, false}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("invalid decisions: got %v, expect %v", got, expect)
+	}
+
+	if err := relay.Add(&wire.FilterAdd{Data: []byte("x")}); bloom.ErrUninitialised != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, bloom.ErrUninitialised)
+	}
+
+	if err := relay.Load(filter.Snapshot()); nil != err {
+		t.Fatal(err)
+	}
+	if !relay.Relaying() {
+		t.Fatal("relay should be turned on by filterload")
+	}
+
+	// chain[1] is caught by the OutPoint updated by chain[0]
+	if got, expect := relay.Decide(txs), []bool{true, false, true, false}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("invalid decisions: got %v, expect %v", got, expect)
+	}
+
+	if err := relay.Add(&wire.FilterAdd{Data: keyHash("unrelated")}); nil != err {
+		t.Fatal(err)
+	}
+	if !relay.ShouldRelay(unrelated) {
+		t.Fatal("tx added by filteradd should be relayed")
+	}
+
+	relay.Clear()
+	if !relay.Relaying() || nil != relay.Filter() {
+		t.Fatal("filterclear should keep relay on without filter")
+	}
+	if got, expect := relay.Decide(txs), []bool{true, true, true, true}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("invalid decisions: got %v, expect %v", got, expect)
+	}
+}
+
+func TestRelay_Load_invalid(t *testing.T) {
+	testCases := []struct {
+		desc string
+		msg  *wire.FilterLoad
+	}{
+		{"too large", &wire.FilterLoad{Bits: make([]byte, bloom.MaxFilterSize+1), HashFuncs: 1}},
+		{"too many hash funcs", &wire.FilterLoad{Bits: make([]byte, 1), HashFuncs: bloom.MaxHashFuncs + 1}},
+	}
+
+	for i, c := range testCases {
+		relay := server.NewRelay(false)
+		if err := relay.Load(c.msg); bloom.ErrInvalidEncoding != err {
+			t.Fatalf("#%d [%s] invalid error: got %v, expect %v", i, c.desc, err,
+				bloom.ErrInvalidEncoding)
+		}
+
+		if relay.Relaying() {
+			t.Fatalf("#%d [%s] rejected filterload shouldn't turn on relay", i,
+				c.desc)
+		}
+	}
+}
+
+func TestRelay_Load_flags(t *testing.T) {
+	testCases := []struct {
+		optIn  bool
+		flags  wire.BloomUpdateType
+		expect wire.BloomUpdateType
+	}{
+		{false, wire.UpdateAll, wire.UpdateAll},
+		{false, wire.UpdateP2PubKeyOrScriptHash, wire.UpdateNone},
+		{false, 200, wire.UpdateNone},
+		{true, wire.UpdateP2PubKeyOrScriptHash, wire.UpdateP2PubKeyOrScriptHash},
+		{true, 200, wire.UpdateNone},
+	}
+
+	for i, c := range testCases {
+		relay := server.NewRelay(false)
+		relay.AllowNonStandardFlags = c.optIn
+
+		msg := &wire.FilterLoad{Bits: make([]byte, 1), HashFuncs: 1, Flags: c.flags}
+		if err := relay.Load(msg); nil != err {
+			t.Fatalf("#%d unexpected error: %v", i, err)
+		}
+
+		if got := relay.Filter().Snapshot().Flags; got != c.expect {
+			t.Fatalf("#%d invalid flags: got %d, expect %d", i, got, c.expect)
+		}
+		if c.flags != msg.Flags {
+			t.Fatalf("#%d the filterload shouldn't be modified", i)
+		}
+	}
+}
+
+func TestRelay_emptyBits(t *testing.T) {
+	relay := server.NewRelay(true)
+	if err := relay.Load(&wire.FilterLoad{Bits: nil, HashFuncs: 1}); nil != err {
+		t.Fatal(err)
+	}
+
+	tx := newTx(btcwire.NewOutPoint(&chainhash.Hash{0x02}, 0), keyHash("any"))
+	if got := relay.Decide([]*btcutil.Tx{tx}); !got[0] {
+		t.Fatal("empty bits should relay everything")
+	}
+}
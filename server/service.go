@@ -0,0 +1,126 @@
+// Package server implements the server side of BIP37, i.e. the support a full
+// node needs to serve filtering peers.
+package server
+
+import (
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// MaxBanScore is the misbehaving score which gets a peer banned at once
+const MaxBanScore = 100
+
+// Action enumerates how to handle a filter message from a peer
+type Action uint8
+
+// Enumerations of actions
+const (
+	// Allow processes the message
+	Allow Action = iota
+	// Ignore drops the message silently
+	Ignore
+	// Disconnect drops the message and disconnects the peer
+	Disconnect
+)
+
+// Decision is how to handle a filter message from a peer
+type Decision struct {
+	Action Action
+	// BanScore is the misbehaving score to punish the peer with, which only
+	// comes with Disconnect
+	BanScore uint32
+}
+
+// banned is the decision for offences getting the peer banned at once
+var banned = Decision{Action: Disconnect, BanScore: MaxBanScore}
+
+// Service decides whether filter messages from peers are acceptable
+// following BIP111 and the behavior of Bitcoin Core
+type Service struct {
+	// BloomEnabled signals whether the local node offers SFNodeBloom
+	BloomEnabled bool
+}
+
+// LocalServices returns the services to advertise concerning bloom
+// filtering
+func (s *Service) LocalServices() wire.ServiceFlag {
+	if s.BloomEnabled {
+		return wire.SFNodeBloom
+	}
+
+	return 0
+}
+
+// Decide decides how to handle the filter message of command from a peer of
+// the negotiated protocol version. Filter messages are always allowed if
+// bloom service is enabled. Otherwise
+//  - filterload and filteradd get the peer disconnected, and banned if the
+//    peer knows BIP111 by version
+//  - filterclear is ignored
+// Non-filter commands are always allowed.
+func (s *Service) Decide(command string, version uint32) Decision {
+	switch command {
+	case wire.CmdFilterLoad, wire.CmdFilterAdd:
+	case wire.CmdFilterClear:
+		if !s.BloomEnabled {
+			return Decision{Action: Ignore}
+		}
+		return Decision{Action: Allow}
+	default:
+		return Decision{Action: Allow}
+	}
+
+	switch {
+	case s.BloomEnabled:
+		return Decision{Action: Allow}
+	case version >= wire.NoBloomVersion:
+		return banned
+	default:
+		return Decision{Action: Disconnect}
+	}
+}
+
+// DecideFilterAdd checks the content of a filteradd following Bitcoin Core,
+// which punishes oversized data or a missing filter
+func (s *Service) DecideFilterAdd(msg *wire.FilterAdd, version uint32,
+	loaded bool) Decision {
+	if d := s.Decide(wire.CmdFilterAdd, version); Allow != d.Action {
+		return d
+	}
+
+	if len(msg.Data) > bloom.MaxFilterAddSize || !loaded {
+		return banned
+	}
+
+	return Decision{Action: Allow}
+}
+
+// DecideFilterLoad checks the content of a filterload following Bitcoin
+// Core, which only punishes filters beyond the size limits of BIP37. Empty
+// bits and unknown flags are allowed, which Relay.Load takes care of.
+func (s *Service) DecideFilterLoad(msg *wire.FilterLoad,
+	version uint32) Decision {
+	if d := s.Decide(wire.CmdFilterLoad, version); Allow != d.Action {
+		return d
+	}
+
+	if !withinSizeConstraints(msg) {
+		return banned
+	}
+
+	return Decision{Action: Allow}
+}
+
+// withinSizeConstraints checks the filter against the size limits of BIP37
+// as IsWithinSizeConstraints of Bitcoin Core
+func withinSizeConstraints(msg *wire.FilterLoad) bool {
+	return len(msg.Bits) <= bloom.MaxFilterSize &&
+		msg.HashFuncs <= bloom.MaxHashFuncs
+}
+
+// PeerSupportsBloom checks if a peer of the negotiated protocol version and
+// services accepts filter messages, where peers prior to BIP111 are assumed
+// to support bloom filtering
+func PeerSupportsBloom(version uint32, services wire.ServiceFlag) bool {
+	return version < wire.NoBloomVersion || 0 != services&wire.SFNodeBloom
+}
@@ -0,0 +1,119 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/server"
+	"github.com/sammyne/bip37/wire"
+)
+
+func TestService_Decide(t *testing.T) {
+	const (
+		oldVersion = wire.NoBloomVersion - 1
+		newVersion = wire.NoBloomVersion
+	)
+
+	testCases := []struct {
+		enabled bool
+		command string
+		version uint32
+		expect  server.Decision
+	}{
+		{true, wire.CmdFilterLoad, newVersion, server.Decision{Action: server.Allow}},
+		{true, wire.CmdFilterAdd, oldVersion, server.Decision{Action: server.Allow}},
+		{true, wire.CmdFilterClear, newVersion, server.Decision{Action: server.Allow}},
+		{false, wire.CmdFilterLoad, newVersion,
+			server.Decision{Action: server.Disconnect, BanScore: server.MaxBanScore}},
+		{false, wire.CmdFilterAdd, newVersion,
+			server.Decision{Action: server.Disconnect, BanScore: server.MaxBanScore}},
+		{false, wire.CmdFilterLoad, oldVersion, server.Decision{Action: server.Disconnect}},
+		{false, wire.CmdFilterAdd, oldVersion, server.Decision{Action: server.Disconnect}},
+		{false, wire.CmdFilterClear, newVersion, server.Decision{Action: server.Ignore}},
+		{false, wire.CmdFilterClear, oldVersion, server.Decision{Action: server.Ignore}},
+		{false, "inv", newVersion, server.Decision{Action: server.Allow}},
+	}
+
+	for i, c := range testCases {
+		s := &server.Service{BloomEnabled: c.enabled}
+		if got := s.Decide(c.command, c.version); got != c.expect {
+			t.Fatalf("#%d invalid decision: got %+v, expect %+v", i, got, c.expect)
+		}
+	}
+}
+
+func TestService_DecideFilterAdd(t *testing.T) {
+	s := &server.Service{BloomEnabled: true}
+
+	testCases := []struct {
+		size   int
+		loaded bool
+		expect server.Action
+	}{
+		{bloom.MaxFilterAddSize, true, server.Allow},
+		{bloom.MaxFilterAddSize + 1, true, server.Disconnect},
+		{1, false, server.Disconnect},
+	}
+
+	for i, c := range testCases {
+		msg := &wire.FilterAdd{Data: make([]byte, c.size)}
+		got := s.DecideFilterAdd(msg, wire.NoBloomVersion, c.loaded)
+		if got.Action != c.expect {
+			t.Fatalf("#%d invalid action: got %v, expect %v", i, got.Action, c.expect)
+		}
+		if server.Disconnect == got.Action && server.MaxBanScore != got.BanScore {
+			t.Fatalf("#%d invalid ban score: got %d", i, got.BanScore)
+		}
+	}
+}
+
+func TestService_DecideFilterLoad(t *testing.T) {
+	s := &server.Service{BloomEnabled: true}
+
+	// only the size limits are punished as Bitcoin Core does
+	testCases := []struct {
+		msg    *wire.FilterLoad
+		expect server.Decision
+	}{
+		{&wire.FilterLoad{Bits: make([]byte, bloom.MaxFilterSize), HashFuncs: bloom.MaxHashFuncs},
+			server.Decision{Action: server.Allow}},
+		{&wire.FilterLoad{Bits: make([]byte, bloom.MaxFilterSize+1), HashFuncs: 1},
+			server.Decision{Action: server.Disconnect, BanScore: server.MaxBanScore}},
+		{&wire.FilterLoad{Bits: make([]byte, 1), HashFuncs: bloom.MaxHashFuncs + 1},
+			server.Decision{Action: server.Disconnect, BanScore: server.MaxBanScore}},
+		{&wire.FilterLoad{Bits: nil, HashFuncs: 1},
+			server.Decision{Action: server.Allow}},
+		{&wire.FilterLoad{Bits: make([]byte, 1), HashFuncs: 1, Flags: wire.UpdateP2PubKeyOrScriptHash},
+			server.Decision{Action: server.Allow}},
+		{&wire.FilterLoad{Bits: make([]byte, 1), HashFuncs: 1, Flags: 200},
+			server.Decision{Action: server.Allow}},
+	}
+
+	for i, c := range testCases {
+		if got := s.DecideFilterLoad(c.msg, wire.NoBloomVersion); got != c.expect {
+			t.Fatalf("#%d invalid decision: got %+v, expect %+v", i, got, c.expect)
+		}
+	}
+
+	if got := (&server.Service{}).LocalServices(); 0 != got {
+		t.Fatalf("unexpected services: %v", got)
+	}
+}
+
+func TestPeerSupportsBloom(t *testing.T) {
+	testCases := []struct {
+		version  uint32
+		services wire.ServiceFlag
+		expect   bool
+	}{
+		{wire.NoBloomVersion - 1, 0, true},
+		{wire.NoBloomVersion, 0, false},
+		{wire.NoBloomVersion, wire.SFNodeBloom, true},
+	}
+
+	for i, c := range testCases {
+		if got := server.PeerSupportsBloom(c.version, c.services); got != c.expect {
+			t.Fatalf("#%d got %v, expect %v", i, got, c.expect)
+		}
+	}
+}
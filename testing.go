@@ -28,17 +28,23 @@ func Unhexlify(str string) []byte {
 }
 
 // ReadBlock reads in the block from the testdata
-func ReadBlock(t *testing.T) *btcwire.MsgBlock {
-	fd, err := os.Open(filepath.Join("..", "testdata", "block.json"))
+func ReadBlock(t testing.TB) *btcwire.MsgBlock {
+	msgBlock := new(btcwire.MsgBlock)
+	ReadTestData(t, "block.json", msgBlock)
+
+	return msgBlock
+}
+
+// ReadTestData decodes the JSON file of the given name from the testdata
+// into v
+func ReadTestData(t testing.TB, name string, v interface{}) {
+	fd, err := os.Open(filepath.Join("..", "testdata", name))
 	if nil != err {
 		t.Fatal(err)
 	}
 	defer fd.Close()
 
-	msgBlock := new(btcwire.MsgBlock)
-	if err := json.NewDecoder(fd).Decode(msgBlock); nil != err {
+	if err := json.NewDecoder(fd).Decode(v); nil != err {
 		t.Fatal(err)
 	}
-
-	return msgBlock
 }
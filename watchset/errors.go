@@ -0,0 +1,6 @@
+package watchset
+
+import "errors"
+
+// ErrInvalidEntry signals a stored entry fails to decode
+var ErrInvalidEntry = errors.New("invalid watch-set entry")
@@ -0,0 +1,75 @@
+package watchset
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// State is the persisted state of a watch-set
+type State struct {
+	// Tweak is the tweak of the regenerated filters, which is kept across
+	// restarts so that peers don't see a fresh filter every time
+	Tweak   uint32  `json:"tweak"`
+	Entries []Entry `json:"entries"`
+}
+
+// Store persists the state of a watch-set
+type Store interface {
+	// Load returns the stored state, which is nil if nothing is stored yet
+	Load() (*State, error)
+	// Save replaces the stored state
+	Save(state *State) error
+}
+
+// FileStore keeps the state as a JSON file on the local disk. The file is
+// replaced atomically by renaming a temporary file upon saving.
+type FileStore struct {
+	Path string
+}
+
+// Load implements Store
+func (s *FileStore) Load() (*State, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if nil != err {
+		return nil, err
+	}
+
+	state := new(State)
+	if err := json.Unmarshal(data, state); nil != err {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Save implements Store
+func (s *FileStore) Save(state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if nil != err {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if nil != err {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); nil != err {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); nil != err {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); nil != err {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.Path)
+}
@@ -0,0 +1,307 @@
+// Package watchset implements a persistent set of the items a wallet watches,
+// from which bloom filters are regenerated deterministically on startup.
+package watchset
+
+import (
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/bloom"
+	"github.com/sammyne/bip37/wire"
+)
+
+// Kind enumerates the kinds of watched items
+type Kind string
+
+// Enumerations of kinds
+const (
+	// KindAddress is an encoded address, whose script address is watched
+	KindAddress Kind = "address"
+	// KindPubKey is a hex-encoded public key, where both the key and its
+	// HASH160 are watched
+	KindPubKey Kind = "pubkey"
+	// KindScript is a hex-encoded script, whose pushed data are watched
+	KindScript Kind = "script"
+	// KindOutPoint is an OutPoint formatted as `txid:index`
+	KindOutPoint Kind = "outpoint"
+)
+
+// Source enumerates where an entry comes from
+type Source string
+
+// Enumerations of sources
+const (
+	// SourceUser is an item added explicitly
+	SourceUser Source = "user"
+	// SourceAuto is an OutPoint added by filter updates of matched txs
+	SourceAuto Source = "auto"
+)
+
+// Entry is a watched item
+type Entry struct {
+	Kind   Kind   `json:"kind"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+}
+
+// WatchSet is a persistent set of watched items, where every change is saved
+// to the store at once. It is safe for concurrent use.
+type WatchSet struct {
+	mtx sync.Mutex
+
+	store Store
+	net   *chaincfg.Params
+	state State
+	// index is the set of entries keyed by kind and value
+	index map[Entry]bool
+}
+
+// AddAddress watches the address
+func (s *WatchSet) AddAddress(addr btcutil.Address) error {
+	return s.add(Entry{KindAddress, addr.EncodeAddress(), SourceUser})
+}
+
+// AddOutPoint watches the OutPoint
+func (s *WatchSet) AddOutPoint(out *btcwire.OutPoint) error {
+	return s.add(Entry{KindOutPoint, out.String(), SourceUser})
+}
+
+// AddPubKey watches the serialized public key
+func (s *WatchSet) AddPubKey(pubKey []byte) error {
+	return s.add(Entry{KindPubKey, hex.EncodeToString(pubKey), SourceUser})
+}
+
+// AddScript watches the data pushed by the script
+func (s *WatchSet) AddScript(script []byte) error {
+	if _, err := txscript.PushedData(script); nil != err {
+		return err
+	}
+
+	return s.add(Entry{KindScript, hex.EncodeToString(script), SourceUser})
+}
+
+// Elements returns the filter elements of all entries in the order of adding,
+// which is deterministic given the stored state
+func (s *WatchSet) Elements() ([][]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.elements()
+}
+
+// Entries returns a copy of all entries in the order of adding
+func (s *WatchSet) Entries() []Entry {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return append([]Entry(nil), s.state.Entries...)
+}
+
+// Filter regenerates the filter holding all elements at false positive rate
+// P. The same state always yields the same filter, since the tweak is stored.
+func (s *WatchSet) Filter(P float64, flags wire.BloomUpdateType) (*bloom.Filter,
+	error) {
+	// take elements and tweak from the same snapshot of the state
+	s.mtx.Lock()
+	elems, err := s.elements()
+	tweak := s.state.Tweak
+	s.mtx.Unlock()
+
+	if nil != err {
+		return nil, err
+	}
+
+	N := uint32(len(elems))
+	if 0 == N {
+		N = 1
+	}
+
+	filter := bloom.New(N, P, flags, tweak)
+	for _, v := range elems {
+		if err := filter.Add(v); nil != err {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
+
+// MatchTxAndUpdate matches tx against filter as bloom.Filter.MatchTxAndUpdate,
+// and records the OutPoints added by the update as SourceAuto entries, so that
+// they survive restarts
+func (s *WatchSet) MatchTxAndUpdate(filter *bloom.Filter,
+	tx *btcutil.Tx) (bool, error) {
+	ok, tracked := filter.MatchTxAndTrack(tx)
+	if 0 == len(tracked) {
+		return ok, nil
+	}
+
+	entries := make([]Entry, len(tracked))
+	for i := range tracked {
+		entries[i] = Entry{KindOutPoint, tracked[i].String(), SourceAuto}
+	}
+
+	return ok, s.add(entries...)
+}
+
+// Tweak returns the tweak of regenerated filters
+func (s *WatchSet) Tweak() uint32 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.state.Tweak
+}
+
+// add appends the entries whose items aren't watched yet, regardless of their
+// sources, and saves the state once. Nothing is appended if saving fails.
+func (s *WatchSet) add(entries ...Entry) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	n := len(s.state.Entries)
+
+	var keys []Entry
+	seen := make(map[Entry]bool, len(entries))
+	for _, e := range entries {
+		key := Entry{Kind: e.Kind, Value: e.Value}
+		if s.index[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		s.state.Entries = append(s.state.Entries, e)
+		keys = append(keys, key)
+	}
+
+	if 0 == len(keys) {
+		return nil
+	}
+
+	if err := s.store.Save(&s.state); nil != err {
+		s.state.Entries = s.state.Entries[:n]
+		return err
+	}
+
+	for _, key := range keys {
+		s.index[key] = true
+	}
+
+	return nil
+}
+
+// elements decodes the filter elements of all entries in order, with the lock
+// held by the caller
+func (s *WatchSet) elements() ([][]byte, error) {
+	var out [][]byte
+	for i := range s.state.Entries {
+		elems, err := elements(&s.state.Entries[i], s.net)
+		if nil != err {
+			return nil, err
+		}
+		out = append(out, elems...)
+	}
+
+	return out, nil
+}
+
+// elements decodes the filter elements of the entry
+func elements(e *Entry, net *chaincfg.Params) ([][]byte, error) {
+	switch e.Kind {
+	case KindAddress:
+		addr, err := btcutil.DecodeAddress(e.Value, net)
+		if nil != err {
+			return nil, ErrInvalidEntry
+		}
+		return [][]byte{addr.ScriptAddress()}, nil
+	case KindPubKey:
+		pubKey, err := hex.DecodeString(e.Value)
+		if nil != err {
+			return nil, ErrInvalidEntry
+		}
+		return [][]byte{pubKey, btcutil.Hash160(pubKey)}, nil
+	case KindScript:
+		script, err := hex.DecodeString(e.Value)
+		if nil != err {
+			return nil, ErrInvalidEntry
+		}
+		pushes, err := txscript.PushedData(script)
+		if nil != err {
+			return nil, ErrInvalidEntry
+		}
+		return pushes, nil
+	case KindOutPoint:
+		out, err := parseOutPoint(e.Value)
+		if nil != err {
+			return nil, err
+		}
+		return [][]byte{bloom.OutPointElement(out)}, nil
+	}
+
+	return nil, ErrInvalidEntry
+}
+
+// parseOutPoint parses an OutPoint formatted by btcwire.OutPoint.String
+func parseOutPoint(s string) (*btcwire.OutPoint, error) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return nil, ErrInvalidEntry
+	}
+
+	hash, err := chainhash.NewHashFromStr(s[:i])
+	if nil != err {
+		return nil, ErrInvalidEntry
+	}
+
+	index, err := strconv.ParseUint(s[i+1:], 10, 32)
+	if nil != err {
+		return nil, ErrInvalidEntry
+	}
+
+	return btcwire.NewOutPoint(hash, uint32(index)), nil
+}
+
+// Open loads the watch-set of the network from store. A fresh one is saved
+// with a tweak drawn from r, which defaults to crypto/rand.Reader if nil.
+func Open(store Store, net *chaincfg.Params, r io.Reader) (*WatchSet, error) {
+	state, err := store.Load()
+	if nil != err {
+		return nil, err
+	}
+
+	if nil == state {
+		tweak, err := bloom.RandomTweak(r)
+		if nil != err {
+			return nil, err
+		}
+
+		state = &State{Tweak: tweak}
+		if err := store.Save(state); nil != err {
+			return nil, err
+		}
+	}
+
+	s := &WatchSet{
+		store: store,
+		net:   net,
+		state: *state,
+		index: make(map[Entry]bool, len(state.Entries)),
+	}
+
+	for i := range state.Entries {
+		e := &state.Entries[i]
+		if _, err := elements(e, net); nil != err {
+			return nil, err
+		}
+		s.index[Entry{Kind: e.Kind, Value: e.Value}] = true
+	}
+
+	return s, nil
+}
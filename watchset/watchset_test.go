@@ -0,0 +1,211 @@
+package watchset_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	btcwire "github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/sammyne/bip37/watchset"
+	"github.com/sammyne/bip37/wire"
+)
+
+var net = &chaincfg.MainNetParams
+
+func open(t *testing.T, path string) *watchset.WatchSet {
+	s, err := watchset.Open(&watchset.FileStore{Path: path}, net,
+		bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04}))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestWatchSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchset.json")
+
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160([]byte("addr")), net)
+	if nil != err {
+		t.Fatal(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	s := open(t, path)
+	if 0x04030201 != s.Tweak() {
+		t.Fatalf("invalid tweak: got %x", s.Tweak())
+	}
+
+	if err := s.AddAddress(addr); nil != err {
+		t.Fatal(err)
+	}
+	if err := s.AddPubKey(bytes.Repeat([]byte{0x02}, 33)); nil != err {
+		t.Fatal(err)
+	}
+	if err := s.AddScript([]byte{txscript.OP_1, 0x02, 0xab, 0xcd}); nil != err {
+		t.Fatal(err)
+	}
+	if err := s.AddOutPoint(btcwire.NewOutPoint(&chainhash.Hash{0x01}, 7)); nil != err {
+		t.Fatal(err)
+	}
+	// duplicates are ignored
+	if err := s.AddAddress(addr); nil != err {
+		t.Fatal(err)
+	}
+
+	if n := len(s.Entries()); 4 != n {
+		t.Fatalf("invalid #(entry): got %d, expect 4", n)
+	}
+
+	filter, err := s.Filter(0.0001, wire.UpdateAll)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// a tx paying to the address
+	tx := btcwire.NewMsgTx(btcwire.TxVersion)
+	tx.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{0x02}, 0), nil, nil))
+	tx.AddTxOut(btcwire.NewTxOut(1000, pkScript))
+
+	txid := tx.TxHash()
+	ok, err := s.MatchTxAndUpdate(filter, btcutil.NewTx(tx))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("tx paying to the watched address should match")
+	}
+
+	entries := s.Entries()
+	if 5 != len(entries) {
+		t.Fatalf("invalid #(entry): got %d, expect 5", len(entries))
+	}
+	auto := watchset.Entry{
+		Kind:   watchset.KindOutPoint,
+		Value:  btcwire.NewOutPoint(&txid, 0).String(),
+		Source: watchset.SourceAuto,
+	}
+	if entries[4] != auto {
+		t.Fatalf("invalid auto-added entry: got %+v, expect %+v", entries[4], auto)
+	}
+
+	expect, err := s.Filter(0.0001, wire.UpdateAll)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// reopen as restarting
+	reopened := open(t, path)
+	if got := reopened.Entries(); !reflect.DeepEqual(got, entries) {
+		t.Fatalf("invalid entries: got %+v, expect %+v", got, entries)
+	}
+
+	got, err := reopened.Filter(0.0001, wire.UpdateAll)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Snapshot(), expect.Snapshot()) {
+		t.Fatal("regenerated filter differs")
+	}
+
+	if !got.MatchOutPoint(btcwire.NewOutPoint(&txid, 0)) {
+		t.Fatal("auto-added OutPoint should survive restarts")
+	}
+}
+
+func TestOpen_invalidEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchset.json")
+
+	data := []byte(`{"tweak":1,"entries":[{"kind":"outpoint","value":"bogus","source":"user"}]}`)
+	if err := ioutil.WriteFile(path, data, 0600); nil != err {
+		t.Fatal(err)
+	}
+
+	_, err := watchset.Open(&watchset.FileStore{Path: path}, net, nil)
+	if watchset.ErrInvalidEntry != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, watchset.ErrInvalidEntry)
+	}
+}
+
+func TestWatchSet_Filter_empty(t *testing.T) {
+	s := open(t, filepath.Join(t.TempDir(), "watchset.json"))
+
+	filter, err := s.Filter(0.0001, wire.UpdateNone)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !filter.Loaded() {
+		t.Fatal("empty watch-set should still yield a filter")
+	}
+}
+
+// countingStore keeps the state in memory and counts the saves
+type countingStore struct {
+	state *watchset.State
+	saves int
+}
+
+func (s *countingStore) Load() (*watchset.State, error) {
+	return s.state, nil
+}
+
+func (s *countingStore) Save(state *watchset.State) error {
+	s.state = state
+	s.saves++
+	return nil
+}
+
+func TestWatchSet_MatchTxAndUpdate_batched(t *testing.T) {
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160([]byte("addr")), net)
+	if nil != err {
+		t.Fatal(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	store := new(countingStore)
+	s, err := watchset.Open(store, net, bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := s.AddAddress(addr); nil != err {
+		t.Fatal(err)
+	}
+
+	filter, err := s.Filter(0.0001, wire.UpdateAll)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	const nOut = 8
+	tx := btcwire.NewMsgTx(btcwire.TxVersion)
+	tx.AddTxIn(btcwire.NewTxIn(btcwire.NewOutPoint(&chainhash.Hash{0x02}, 0), nil, nil))
+	for i := 0; i < nOut; i++ {
+		tx.AddTxOut(btcwire.NewTxOut(1000, pkScript))
+	}
+
+	saves := store.saves
+	if ok, err := s.MatchTxAndUpdate(filter, btcutil.NewTx(tx)); nil != err {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("tx paying to the watched address should match")
+	}
+
+	if got := store.saves - saves; 1 != got {
+		t.Fatalf("tracked OutPoints should be saved at once: got %d saves", got)
+	}
+	if got := len(store.state.Entries); 1+nOut != got {
+		t.Fatalf("invalid #(entry): got %d, expect %d", got, 1+nOut)
+	}
+}
@@ -8,4 +8,22 @@ const (
 	UpdateNone         BloomUpdateType = 0
 	UpdateAll          BloomUpdateType = 1
 	UpdateP2PubKeyOnly BloomUpdateType = 2
+	// UpdateP2PubKeyOrScriptHash is a NON-STANDARD extension of
+	// UpdateP2PubKeyOnly, which also updates the filter with OutPoints of
+	// matched P2SH and P2WSH outputs, e.g. those wrapping a multisig. Peers
+	// following BIP37 won't apply any update for it, so it's only meaningful
+	// between nodes running this library that opt in explicitly.
+	UpdateP2PubKeyOrScriptHash BloomUpdateType = 3
 )
+
+// IsValid checks if the policy is a known one, standard or not
+func (t BloomUpdateType) IsValid() bool {
+	return t <= UpdateP2PubKeyOrScriptHash
+}
+
+// IsStandard checks if the policy is specified by BIP37. Servers should
+// apply no update for non-standard policies from peers unless opted in
+// explicitly, as server.Relay does.
+func (t BloomUpdateType) IsStandard() bool {
+	return t <= UpdateP2PubKeyOnly
+}
@@ -4,9 +4,9 @@ package wire
 
 import "strconv"
 
-const _BloomUpdateType_name = "UpdateNoneUpdateAllUpdateP2PubKeyOnly"
+const _BloomUpdateType_name = "UpdateNoneUpdateAllUpdateP2PubKeyOnlyUpdateP2PubKeyOrScriptHash"
 
-var _BloomUpdateType_index = [...]uint8{0, 10, 19, 37}
+var _BloomUpdateType_index = [...]uint8{0, 10, 19, 37, 63}
 
 func (i BloomUpdateType) String() string {
 	if i >= BloomUpdateType(len(_BloomUpdateType_index)-1) {
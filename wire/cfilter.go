@@ -0,0 +1,251 @@
+package wire
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	btcwire "github.com/btcsuite/btcd/wire"
+)
+
+// FilterType enumerates the types of compact block filters
+type FilterType uint8
+
+// FilterTypeBasic is the basic filter type of BIP158
+const FilterTypeBasic FilterType = 0
+
+// Limits of the messages of BIP157
+const (
+	// MaxCFilterDataSize limits the size of a serialized filter in bytes
+	MaxCFilterDataSize = 256 * 1024
+	// MaxCFHeadersPerMsg limits the number of filter hashes in a cfheaders
+	MaxCFHeadersPerMsg = 2000
+	// MaxCFiltersPerQuery limits the number of blocks queried by a
+	// getcfilters
+	MaxCFiltersPerQuery = 1000
+	// MaxCFCheckptsPerMsg limits the number of filter headers in a cfcheckpt
+	MaxCFCheckptsPerMsg = 100000
+	// CFCheckptInterval is the interval in blocks between checkpoints
+	CFCheckptInterval = 1000
+)
+
+// GetCFilters requests the filters of blocks from StartHeight up to the one
+// of StopHash.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0157.mediawiki#getcfilters
+type GetCFilters struct {
+	FilterType  FilterType
+	StartHeight uint32
+	StopHash    chainhash.Hash
+}
+
+// Decode reads the message from r
+func (msg *GetCFilters) Decode(r io.Reader) error {
+	return readElements(r, &msg.FilterType, &msg.StartHeight, &msg.StopHash)
+}
+
+// Encode writes the message into w
+func (msg *GetCFilters) Encode(w io.Writer) error {
+	return writeElements(w, msg.FilterType, msg.StartHeight, &msg.StopHash)
+}
+
+// CFilter carries the filter of a block.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0157.mediawiki#cfilter
+type CFilter struct {
+	FilterType FilterType
+	BlockHash  chainhash.Hash
+	Filter     []byte
+}
+
+// Decode reads the message from r
+func (msg *CFilter) Decode(r io.Reader) error {
+	if err := readElements(r, &msg.FilterType, &msg.BlockHash); nil != err {
+		return err
+	}
+
+	filter, err := btcwire.ReadVarBytes(r, 0, MaxCFilterDataSize, "filter")
+	if nil != err {
+		return err
+	}
+	msg.Filter = filter
+
+	return nil
+}
+
+// Encode writes the message into w
+func (msg *CFilter) Encode(w io.Writer) error {
+	if len(msg.Filter) > MaxCFilterDataSize {
+		return ErrTooLarge
+	}
+
+	if err := writeElements(w, msg.FilterType, &msg.BlockHash); nil != err {
+		return err
+	}
+
+	return btcwire.WriteVarBytes(w, 0, msg.Filter)
+}
+
+// GetCFHeaders requests the filter hashes of blocks from StartHeight up to
+// the one of StopHash, together with the filter header preceding them.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0157.mediawiki#getcfheaders
+type GetCFHeaders struct {
+	FilterType  FilterType
+	StartHeight uint32
+	StopHash    chainhash.Hash
+}
+
+// Decode reads the message from r
+func (msg *GetCFHeaders) Decode(r io.Reader) error {
+	return readElements(r, &msg.FilterType, &msg.StartHeight, &msg.StopHash)
+}
+
+// Encode writes the message into w
+func (msg *GetCFHeaders) Encode(w io.Writer) error {
+	return writeElements(w, msg.FilterType, msg.StartHeight, &msg.StopHash)
+}
+
+// CFHeaders carries the filter hashes of consecutive blocks, which chain into
+// filter headers starting from PrevFilterHeader.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0157.mediawiki#cfheaders
+type CFHeaders struct {
+	FilterType       FilterType
+	StopHash         chainhash.Hash
+	PrevFilterHeader chainhash.Hash
+	FilterHashes     []chainhash.Hash
+}
+
+// Decode reads the message from r
+func (msg *CFHeaders) Decode(r io.Reader) error {
+	if err := readElements(r, &msg.FilterType, &msg.StopHash,
+		&msg.PrevFilterHeader); nil != err {
+		return err
+	}
+
+	hashes, err := readHashes(r, MaxCFHeadersPerMsg)
+	if nil != err {
+		return err
+	}
+	msg.FilterHashes = hashes
+
+	return nil
+}
+
+// Encode writes the message into w
+func (msg *CFHeaders) Encode(w io.Writer) error {
+	if err := writeElements(w, msg.FilterType, &msg.StopHash,
+		&msg.PrevFilterHeader); nil != err {
+		return err
+	}
+
+	return writeHashes(w, msg.FilterHashes, MaxCFHeadersPerMsg)
+}
+
+// GetCFCheckpt requests the filter headers at every CFCheckptInterval blocks
+// up to the one of StopHash.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0157.mediawiki#getcfcheckpt
+type GetCFCheckpt struct {
+	FilterType FilterType
+	StopHash   chainhash.Hash
+}
+
+// Decode reads the message from r
+func (msg *GetCFCheckpt) Decode(r io.Reader) error {
+	return readElements(r, &msg.FilterType, &msg.StopHash)
+}
+
+// Encode writes the message into w
+func (msg *GetCFCheckpt) Encode(w io.Writer) error {
+	return writeElements(w, msg.FilterType, &msg.StopHash)
+}
+
+// CFCheckpt carries the filter headers at heights of CFCheckptInterval, 2*
+// CFCheckptInterval and so on.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0157.mediawiki#cfcheckpt
+type CFCheckpt struct {
+	FilterType    FilterType
+	StopHash      chainhash.Hash
+	FilterHeaders []chainhash.Hash
+}
+
+// Decode reads the message from r
+func (msg *CFCheckpt) Decode(r io.Reader) error {
+	if err := readElements(r, &msg.FilterType, &msg.StopHash); nil != err {
+		return err
+	}
+
+	headers, err := readHashes(r, MaxCFCheckptsPerMsg)
+	if nil != err {
+		return err
+	}
+	msg.FilterHeaders = headers
+
+	return nil
+}
+
+// Encode writes the message into w
+func (msg *CFCheckpt) Encode(w io.Writer) error {
+	if err := writeElements(w, msg.FilterType, &msg.StopHash); nil != err {
+		return err
+	}
+
+	return writeHashes(w, msg.FilterHeaders, MaxCFCheckptsPerMsg)
+}
+
+// readElements reads little-endian fixed-size elements from r
+func readElements(r io.Reader, elements ...interface{}) error {
+	for _, v := range elements {
+		if err := binary.Read(r, binary.LittleEndian, v); nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readHashes reads a var-int counted list of hashes of at most max ones
+func readHashes(r io.Reader, max uint64) ([]chainhash.Hash, error) {
+	n, err := btcwire.ReadVarInt(r, 0)
+	if nil != err {
+		return nil, err
+	} else if n > max {
+		return nil, ErrTooLarge
+	}
+
+	hashes := make([]chainhash.Hash, n)
+	for i := range hashes {
+		if _, err := io.ReadFull(r, hashes[i][:]); nil != err {
+			return nil, err
+		}
+	}
+
+	return hashes, nil
+}
+
+// writeElements writes little-endian fixed-size elements into w
+func writeElements(w io.Writer, elements ...interface{}) error {
+	for _, v := range elements {
+		if err := binary.Write(w, binary.LittleEndian, v); nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeHashes writes a var-int counted list of hashes of at most max ones
+func writeHashes(w io.Writer, hashes []chainhash.Hash, max int) error {
+	if len(hashes) > max {
+		return ErrTooLarge
+	}
+
+	if err := btcwire.WriteVarInt(w, 0, uint64(len(hashes))); nil != err {
+		return err
+	}
+
+	for i := range hashes {
+		if _, err := w.Write(hashes[i][:]); nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package wire_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/sammyne/bip37/wire"
+)
+
+// message is the common interface of messages of BIP157
+type message interface {
+	Decode(r io.Reader) error
+	Encode(w io.Writer) error
+}
+
+func TestCFilterMessages(t *testing.T) {
+	stop := chainhash.DoubleHashH([]byte("stop"))
+	prev := chainhash.DoubleHashH([]byte("prev"))
+
+	testCases := []struct {
+		msg   message
+		empty message
+		size  int
+	}{
+		{
+			&wire.GetCFilters{FilterType: wire.FilterTypeBasic, StartHeight: 123, StopHash: stop},
+			new(wire.GetCFilters),
+			1 + 4 + 32,
+		},
+		{
+			&wire.CFilter{BlockHash: stop, Filter: []byte{0x01, 0x9d, 0xfc, 0xa8}},
+			new(wire.CFilter),
+			1 + 32 + 1 + 4,
+		},
+		{
+			&wire.GetCFHeaders{StartHeight: 1, StopHash: stop},
+			new(wire.GetCFHeaders),
+			1 + 4 + 32,
+		},
+		{
+			&wire.CFHeaders{StopHash: stop, PrevFilterHeader: prev, FilterHashes: []chainhash.Hash{stop, prev}},
+			new(wire.CFHeaders),
+			1 + 32 + 32 + 1 + 2*32,
+		},
+		{
+			&wire.GetCFCheckpt{StopHash: stop},
+			new(wire.GetCFCheckpt),
+			1 + 32,
+		},
+		{
+			&wire.CFCheckpt{StopHash: stop, FilterHeaders: []chainhash.Hash{prev}},
+			new(wire.CFCheckpt),
+			1 + 32 + 1 + 32,
+		},
+	}
+
+	for i, c := range testCases {
+		var buf bytes.Buffer
+		if err := c.msg.Encode(&buf); nil != err {
+			t.Fatalf("#%d unexpected error: %v", i, err)
+		}
+
+		if buf.Len() != c.size {
+			t.Fatalf("#%d invalid size: got %d, expect %d", i, buf.Len(), c.size)
+		}
+
+		if err := c.empty.Decode(bytes.NewReader(buf.Bytes())); nil != err {
+			t.Fatalf("#%d unexpected error: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(c.empty, c.msg) {
+			t.Fatalf("#%d invalid decoding: got %v, expect %v", i, c.empty, c.msg)
+		}
+	}
+}
+
+func TestCFHeaders_tooLarge(t *testing.T) {
+	msg := &wire.CFHeaders{
+		FilterHashes: make([]chainhash.Hash, wire.MaxCFHeadersPerMsg+1),
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf); wire.ErrTooLarge != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, wire.ErrTooLarge)
+	}
+
+	// fake a count beyond the limit
+	encoded := append(make([]byte, 1+32+32), 0xfd, 0xd1, 0x07)
+	if err := new(wire.CFHeaders).Decode(bytes.NewReader(encoded)); wire.ErrTooLarge != err {
+		t.Fatalf("invalid error: got %v, expect %v", err, wire.ErrTooLarge)
+	}
+}
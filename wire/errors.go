@@ -0,0 +1,6 @@
+package wire
+
+import "errors"
+
+// ErrTooLarge signals the message exceeds the limits of the protocol
+var ErrTooLarge = errors.New("message too large")
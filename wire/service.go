@@ -0,0 +1,20 @@
+package wire
+
+// ServiceFlag is the bit field of services advertised by a node in its
+// version message
+type ServiceFlag uint64
+
+// SFNodeBloom signals the node supports bloom filtering as BIP111 specifies
+const SFNodeBloom ServiceFlag = 1 << 2
+
+// NoBloomVersion is the protocol version since which nodes must advertise
+// SFNodeBloom to accept filter messages.
+// Detail sees https://github.com/bitcoin/bips/blob/master/bip-0111.mediawiki
+const NoBloomVersion uint32 = 70011
+
+// Commands of the filter messages
+const (
+	CmdFilterLoad  = "filterload"
+	CmdFilterAdd   = "filteradd"
+	CmdFilterClear = "filterclear"
+)